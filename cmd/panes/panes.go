@@ -1,21 +1,112 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
 
 	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/embeddings"
+	"github.com/aaryansinhaa/panes/utils/gateway"
 	"github.com/aaryansinhaa/panes/utils/mcp"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
 	"github.com/aaryansinhaa/panes/utils/server"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/supervisor"
+	"github.com/aaryansinhaa/panes/utils/tools"
+	"github.com/aaryansinhaa/panes/utils/types"
+	// Tool packs register their providers from init, compile them in with a blank import here
+	// and enable them under mcp_server.tool_providers
 )
 
 func main() {
-	fmt.Println("Hello, from Panes!")
+	// stdout belongs to the stdio MCP transport, so everything else goes to stderr
+	fmt.Fprintln(os.Stderr, "Hello, from Panes!")
 
 	// loading config
 	cfg := config.MustLoadConfig()
 
-	mcp.Server()
-	//start the server
-	server.LoadServer(cfg)
+	// Connect to SQLite database, shared by the MCP server and the REST API
+	store, err := storage.LoadSQLiteStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to storage: %v", err)
+	}
+	defer store.Close()
 
+	// Semantic search is optional, it needs an embeddings endpoint
+	index, err := embeddings.New(cfg.Embeddings, store)
+	if err != nil {
+		store.Close()
+		log.Fatalf("Failed to set up semantic search: %v", err)
+	}
+	semanticTools := 0
+	if index != nil {
+		semanticTools = 1
+	}
+
+	// The built-in file tools index the files they write like uploads
+	provider.Register(mcp.NewFileToolProvider(index))
+
+	// Tools declared in the config are served by their own providers
+	declared := []struct {
+		provider provider.ToolProvider
+		tools    int
+	}{
+		{tools.NewCommandProvider(cfg.CommandTools), len(cfg.CommandTools)},
+		{tools.NewHTTPProvider(cfg.HTTPTools), len(cfg.HTTPTools)},
+		{mcp.NewSemanticSearchProvider(index), semanticTools},
+	}
+	for _, d := range declared {
+		provider.Register(d.provider)
+		if d.tools > 0 && !slices.Contains(cfg.MCPServer.ToolProviders, d.provider.Name()) {
+			slog.Warn("Tools are configured but their provider is not enabled in mcp_server.tool_providers", "provider", d.provider.Name())
+		}
+	}
+
+	// Downstream MCP servers are aggregated in gateway mode, one that cannot be reached is logged and left out
+	gw, errs := gateway.Connect(context.Background(), cfg.DownstreamServers)
+	for _, err := range errs {
+		slog.Error("Failed to connect downstream MCP server", "error", err)
+		logErr := store.CreateLogEntry(types.LogEntry{Message: err.Error(), Type: "error", Action: "gateway", ClientName: "admin"})
+		if logErr != nil {
+			slog.Error("Failed to log gateway error", "error", logErr)
+		}
+	}
+	defer gw.Close()
+
+	mcpServer, err := mcp.NewServer(store, cfg.MCPServer, gw)
+	if err != nil {
+		gw.Close()
+		store.Close()
+		log.Fatalf("Failed to create MCP server: %v", err)
+	}
+	port := server.Port(cfg)
+
+	// Run the enabled components together until SIGTERM/SIGINT or until one of them stops
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	components := supervisor.New(store, cfg.ShutdownTimeout)
+	if cfg.MCPServer.Enabled(config.TransportStdio) {
+		components.Add("stdio MCP server", mcpServer.ServeStdio)
+	}
+	components.Add("HTTP server", func(ctx context.Context) error {
+		return server.LoadServer(ctx, cfg, store, mcpServer, index, port)
+	})
+
+	// files uploaded before semantic search was enabled are embedded while Panes runs, uploads in the background
+	if index != nil {
+		components.Add("semantic index", index.Run)
+	}
+
+	if err := components.Run(ctx); err != nil {
+		gw.Close()
+		store.Close()
+		log.Fatalf("Panes stopped with errors: %v", err)
+	}
 }
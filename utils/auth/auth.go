@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// apiKeyPrefix marks Panes API keys so they are easy to recognise in configs and secret scanners
+const apiKeyPrefix = "pns_"
+
+type contextKey struct{}
+
+// HashAPIKey returns the hash stored in clients.client_api_hash for an API key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a new random API key, only its hash should ever be stored
+func GenerateAPIKey() (string, error) {
+	key, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + key, nil
+}
+
+// NewClientID returns a new random client ID
+func NewClientID() (string, error) {
+	return randomHex(16)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// WithClient attaches the authenticated client to the context
+func WithClient(ctx context.Context, client types.Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// ClientFromContext returns the authenticated client of a request, if there is one
+func ClientFromContext(ctx context.Context) (types.Client, bool) {
+	client, ok := ctx.Value(contextKey{}).(types.Client)
+	return client, ok
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// AdminMiddleware requires the admin key as a bearer token, it guards the REST API that manages clients and permissions
+func AdminMiddleware(adminKey string, next http.Handler) http.Handler {
+	want := sha256.Sum256([]byte(adminKey))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="panes-admin"`)
+			http.Error(w, "Missing bearer admin key", http.StatusUnauthorized)
+			return
+		}
+		got := sha256.Sum256([]byte(key))
+		if subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+			slog.Warn("Rejected REST API request with invalid admin key", "remote", r.RemoteAddr, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="panes-admin", error="invalid_token"`)
+			http.Error(w, "Invalid admin key", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware requires a bearer API key belonging to an active client and attaches that client to the request context
+func Middleware(store *storage.SQLite, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="panes"`)
+			http.Error(w, "Missing bearer API key", http.StatusUnauthorized)
+			return
+		}
+
+		client, err := store.GetClientByAPIHash(HashAPIKey(key))
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("Rejected MCP request with unknown API key", "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="panes", error="invalid_token"`)
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not verify API key", http.StatusInternalServerError)
+			return
+		}
+		if !client.Active {
+			slog.Warn("Rejected MCP request from deactivated client", "client", client.ClientID)
+			http.Error(w, "Client is deactivated", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClient(r.Context(), client)))
+	})
+}
@@ -2,14 +2,132 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"slices"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type HTTPServerConfig struct {
 	Address string `yaml:"address"`
+	Port    string `yaml:"port"` // asked for on startup when empty, unless stdin carries the stdio transport
+	// AdminKey is the bearer token the REST API under /api/ requires, ${VAR} references are replaced from the
+	// environment. Without it the REST API is only served when no HTTP MCP transport shares the listener
+	AdminKey string `yaml:"admin_key"`
+}
+
+// MCP transports that can be listed in MCPServerConfig.Transports
+const (
+	TransportStdio          = "stdio"
+	TransportStreamableHTTP = "streamable_http"
+	TransportSSE            = "sse"
+)
+
+type MCPServerConfig struct {
+	Transports      []string `yaml:"transports" env-default:"streamable_http"` // any of stdio, streamable_http, sse
+	EndpointPath    string   `yaml:"endpoint_path" env-default:"/mcp"`         // Streamable HTTP endpoint on the HTTP server
+	SSEEndpoint     string   `yaml:"sse_endpoint" env-default:"/sse"`          // legacy SSE stream endpoint
+	MessageEndpoint string   `yaml:"message_endpoint" env-default:"/message"`  // legacy SSE message endpoint
+	ToolProviders   []string `yaml:"tool_providers" env-default:"files"`       // registered tool providers whose tools are served
+	PageSize        int      `yaml:"page_size" env-default:"100"`              // most resources, tools or prompts in one list response
+	// SessionIdleTTL ends Streamable HTTP sessions without a request for that long, clients that go away rarely say so
+	SessionIdleTTL time.Duration `yaml:"session_idle_ttl" env-default:"30m"`
+}
+
+// Enabled reports whether the given MCP transport is listed in the configuration
+func (c MCPServerConfig) Enabled(transport string) bool {
+	return slices.Contains(c.Transports, transport)
+}
+
+// ToolArgument declares an argument of a tool declared in the config, it becomes part of the tool's input schema
+type ToolArgument struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"` // string, number, integer or boolean, string when empty
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Enum        []string `yaml:"enum"`    // allowed values of a string argument
+	Pattern     string   `yaml:"pattern"` // regular expression a string argument must match
+	// AllowLeadingDash lets a command tool pass values starting with "-", which the executable could take for an option
+	AllowLeadingDash bool `yaml:"allow_leading_dash"`
+}
+
+// CommandToolConfig declares an MCP tool that runs a local executable, without a shell
+type CommandToolConfig struct {
+	Name           string         `yaml:"name"`
+	Description    string         `yaml:"description"`
+	Command        string         `yaml:"command"`
+	Args           []string       `yaml:"args"` // {{argument}} placeholders are replaced, an element using a missing optional argument is dropped
+	WorkingDir     string         `yaml:"working_dir"`
+	Timeout        time.Duration  `yaml:"timeout"`          // 30s when empty
+	Env            []string       `yaml:"env"`              // names of the environment variables passed on to the command, nothing else is
+	MaxOutputBytes int            `yaml:"max_output_bytes"` // 64 KB when empty, shared by stdout and stderr
+	Arguments      []ToolArgument `yaml:"arguments"`
+}
+
+// HTTPToolConfig declares an MCP tool that calls an HTTP endpoint
+type HTTPToolConfig struct {
+	Name             string            `yaml:"name"`
+	Description      string            `yaml:"description"`
+	Method           string            `yaml:"method"`             // GET when empty
+	URL              string            `yaml:"url"`                // {{argument}} placeholders are replaced with escaped values
+	Headers          map[string]string `yaml:"headers"`            // ${VAR} references are replaced from the environment, for secrets
+	Timeout          time.Duration     `yaml:"timeout"`            // 30s when empty
+	MaxResponseBytes int               `yaml:"max_response_bytes"` // 64 KB when empty
+	Extract          string            `yaml:"extract"`            // JSONPath-style selection of the response, e.g. $.items[0].name
+	Arguments        []ToolArgument    `yaml:"arguments"`          // sent as a JSON body for methods other than GET, HEAD and DELETE
+}
+
+// Downstream transports a gateway server can be reached over
+const (
+	DownstreamStdio          = "stdio"
+	DownstreamStreamableHTTP = "streamable_http"
+	DownstreamSSE            = "sse"
+)
+
+// DownstreamServerConfig declares an MCP server whose tools, resources and prompts Panes re-exports under its name
+type DownstreamServerConfig struct {
+	Name      string            `yaml:"name"`      // namespace prefix, letters, digits, - and _ only
+	Transport string            `yaml:"transport"` // stdio, streamable_http or sse, stdio when a command is set and streamable_http otherwise
+	Command   string            `yaml:"command"`   // stdio: executable launched as a subprocess
+	Args      []string          `yaml:"args"`      // stdio: arguments of the executable
+	Env       map[string]string `yaml:"env"`       // stdio: environment besides PATH and HOME, ${VAR} references are replaced from Panes' environment
+	URL       string            `yaml:"url"`       // streamable_http and sse: endpoint of the server
+	Headers   map[string]string `yaml:"headers"`   // streamable_http and sse: ${VAR} references are replaced from the environment
+}
+
+// ExpandEnv replaces the ${VAR} references of a config value from the environment, failing on unset variables
+func ExpandEnv(value string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		variable := envReference.FindStringSubmatch(reference)[1]
+		resolved, ok := os.LookupEnv(variable)
+		if !ok {
+			missing = append(missing, variable)
+		}
+		return resolved
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("unset environment variables %v", missing)
+	}
+	return expanded, nil
+}
+
+// envReference matches ${VAR} in config values
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// EmbeddingsConfig points the optional semantic search at an OpenAI-compatible embeddings endpoint
+type EmbeddingsConfig struct {
+	URL          string        `yaml:"url"`                             // e.g. http://localhost:11434/v1/embeddings, semantic search is off when empty
+	Model        string        `yaml:"model"`                           // embedding model name sent with every request
+	APIKey       string        `yaml:"api_key"`                         // sent as a bearer token, ${VAR} references are replaced from the environment
+	ChunkSize    int           `yaml:"chunk_size" env-default:"1000"`   // most characters of file text embedded together
+	ChunkOverlap int           `yaml:"chunk_overlap" env-default:"200"` // characters repeated at the start of the next chunk
+	BatchSize    int           `yaml:"batch_size" env-default:"32"`     // most chunks sent in one request
+	Timeout      time.Duration `yaml:"timeout" env-default:"60s"`       // per request
 }
 
 type Config struct {
@@ -18,6 +136,17 @@ type Config struct {
 	Description string           `yaml:"description"`
 	StoragePath string           `yaml:"storage_path"`
 	HTTPServer  HTTPServerConfig `yaml:"http_server"`
+	MCPServer   MCPServerConfig  `yaml:"mcp_server"`
+	// CommandTools are served by the "commands" tool provider
+	CommandTools []CommandToolConfig `yaml:"command_tools"`
+	// HTTPTools are served by the "http" tool provider
+	HTTPTools []HTTPToolConfig `yaml:"http_tools"`
+	// DownstreamServers are aggregated by the MCP gateway
+	DownstreamServers []DownstreamServerConfig `yaml:"downstream_servers"`
+	// Embeddings enables semantic search over file text
+	Embeddings EmbeddingsConfig `yaml:"embeddings"`
+	// ShutdownTimeout is how long the running components get to stop after SIGTERM or SIGINT
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
 }
 
 func MustLoadConfig() *Config {
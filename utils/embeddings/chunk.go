@@ -0,0 +1,31 @@
+package embeddings
+
+import "strings"
+
+// chunk splits text into pieces of at most size characters on word boundaries, each starting with up to overlap
+// characters of the end of the previous one. A single word longer than size is a chunk of its own
+func chunk(text string, size, overlap int) []string {
+	words := strings.Fields(text)
+	var chunks []string
+	for start := 0; start < len(words); {
+		// length counts the words taken so far with the space after each of them
+		end, length := start, 0
+		for end < len(words) && (end == start || length+len(words[end]) <= size) {
+			length += len(words[end]) + 1
+			end++
+		}
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			break
+		}
+
+		// the next chunk starts far enough back to repeat the overlap, but always moves forward
+		next, repeated := end, 0
+		for next > start+1 && repeated+len(words[next-1]) <= overlap {
+			next--
+			repeated += len(words[next]) + 1
+		}
+		start = next
+	}
+	return chunks
+}
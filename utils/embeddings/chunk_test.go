@@ -0,0 +1,63 @@
+package embeddings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+		want    []string
+	}{
+		{"empty", "  \n ", 10, 2, nil},
+		{"fits in one chunk", "one two three", 20, 5, []string{"one two three"}},
+		{"exact size", "aaaa bbbb", 9, 0, []string{"aaaa bbbb"}},
+		{"no overlap", "aaaa bbbb cccc dddd", 9, 0, []string{"aaaa bbbb", "cccc dddd"}},
+		{"overlap repeats the last word", "aaaa bbbb cccc dddd", 9, 5, []string{"aaaa bbbb", "bbbb cccc", "cccc dddd"}},
+		{"overlap of exactly a word", "aaaa bbbb cccc", 9, 4, []string{"aaaa bbbb", "bbbb cccc"}},
+		{"overlap shorter than a word repeats nothing", "aaaa bbbb cccc", 9, 3, []string{"aaaa bbbb", "cccc"}},
+		{"long word is a chunk of its own", "a verylongword b", 5, 0, []string{"a", "verylongword", "b"}},
+		{"overlap never stalls", "aa bb cc", 5, 4, []string{"aa bb", "bb cc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunk(tt.text, tt.size, tt.overlap); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunk(%q, %d, %d) = %q, want %q", tt.text, tt.size, tt.overlap, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkCoversEveryWord(t *testing.T) {
+	words := strings.Fields(strings.Repeat("lorem ipsum dolor sit amet consectetur adipiscing elit ", 50))
+	const size, overlap = 40, 12
+	chunks := chunk(strings.Join(words, " "), size, overlap)
+
+	// each chunk starts after the start of the previous one, at most overlap characters before its end
+	start, end := -1, 0
+	for i, c := range chunks {
+		if len(c) > size {
+			t.Fatalf("chunk %d is %d characters, more than %d", i, len(c), size)
+		}
+		n := len(strings.Fields(c))
+		next := end
+		for next > start+1 && strings.Join(words[next:min(next+n, len(words))], " ") != c {
+			next--
+		}
+		if strings.Join(words[next:min(next+n, len(words))], " ") != c {
+			t.Fatalf("chunk %d %q does not continue the text after word %d", i, c, end)
+		}
+		if repeated := strings.Join(words[next:end], " "); len(repeated) > overlap {
+			t.Fatalf("chunk %d repeats %q, more than %d characters", i, repeated, overlap)
+		}
+		start, end = next, next+n
+	}
+	if end != len(words) {
+		t.Errorf("chunks end at word %d of %d", end, len(words))
+	}
+}
@@ -0,0 +1,69 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestEndpoint serves an embeddings endpoint answering with respond, and a client calling it
+func newTestEndpoint(t *testing.T, respond func(w http.ResponseWriter, request embeddingRequest)) *client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q, want the API key", got)
+		}
+		var request embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		if request.Model != "test-model" {
+			t.Errorf("model = %q, want test-model", request.Model)
+		}
+		respond(w, request)
+	}))
+	t.Cleanup(srv.Close)
+	return &client{url: srv.URL, model: "test-model", apiKey: "sk-test", http: srv.Client()}
+}
+
+func TestEmbedOrdersVectorsByIndex(t *testing.T) {
+	c := newTestEndpoint(t, func(w http.ResponseWriter, request embeddingRequest) {
+		// answered in reverse, each vector holds the position of its input
+		io.WriteString(w, `{"data":[{"index":2,"embedding":[2]},{"index":0,"embedding":[0]},{"index":1,"embedding":[1]}]}`)
+	})
+
+	vectors, err := c.embed(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if want := [][]float32{{0}, {1}, {2}}; !reflect.DeepEqual(vectors, want) {
+		t.Errorf("vectors = %v, want %v", vectors, want)
+	}
+}
+
+func TestEmbedRejectsCountMismatch(t *testing.T) {
+	c := newTestEndpoint(t, func(w http.ResponseWriter, request embeddingRequest) {
+		io.WriteString(w, `{"data":[{"index":0,"embedding":[0]}]}`)
+	})
+
+	_, err := c.embed(context.Background(), []string{"a", "b"})
+	if err == nil || !strings.Contains(err.Error(), "1 vectors for 2 inputs") {
+		t.Errorf("err = %v, want a count mismatch", err)
+	}
+}
+
+func TestEmbedReportsErrorStatus(t *testing.T) {
+	c := newTestEndpoint(t, func(w http.ResponseWriter, request embeddingRequest) {
+		http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+	})
+
+	_, err := c.embed(context.Background(), []string{"a"})
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("err = %v, want the status and message of the endpoint", err)
+	}
+}
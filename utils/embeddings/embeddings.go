@@ -0,0 +1,202 @@
+// Package embeddings keeps an index of embedded file text chunks for semantic search
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// ErrNotConfigured is returned by searches when no embeddings endpoint is configured
+var ErrNotConfigured = errors.New("semantic search is not configured, set embeddings.url and embeddings.model")
+
+// Index embeds the text of the uploaded files and searches it by meaning
+type Index struct {
+	client    *client
+	store     *storage.SQLite
+	chunkSize int
+	overlap   int
+	batchSize int
+	timeout   time.Duration
+
+	mu sync.Mutex
+	// ctx ends background indexing, it is the context of Run once Panes runs
+	ctx context.Context
+	// indexing holds the files being embedded in the background, with the latest text to embed next, nil when none
+	indexing   map[int64]*queuedText
+	background sync.WaitGroup
+}
+
+// queuedText is the text of a file uploaded again while its previous text was being embedded
+type queuedText struct {
+	file types.FileMetadata
+	text string
+}
+
+// New creates the index described by the config, nil when no endpoint is configured
+func New(cfg config.EmbeddingsConfig, store *storage.SQLite) (*Index, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("embeddings: model is required")
+	}
+	if cfg.ChunkSize <= 0 || cfg.ChunkOverlap < 0 || cfg.ChunkOverlap >= cfg.ChunkSize {
+		return nil, fmt.Errorf("embeddings: chunk_overlap must be smaller than chunk_size")
+	}
+	apiKey, err := config.ExpandEnv(cfg.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: api_key: %w", err)
+	}
+	return &Index{
+		client:    &client{url: cfg.URL, model: cfg.Model, apiKey: apiKey, http: &http.Client{Timeout: cfg.Timeout}},
+		store:     store,
+		chunkSize: cfg.ChunkSize,
+		overlap:   cfg.ChunkOverlap,
+		batchSize: max(cfg.BatchSize, 1),
+		timeout:   cfg.Timeout,
+		ctx:       context.Background(),
+		indexing:  make(map[int64]*queuedText),
+	}, nil
+}
+
+// IndexFile replaces the embedded chunks of a file with those of its current text, empty text removes them
+func (x *Index) IndexFile(ctx context.Context, file types.FileMetadata, text string) error {
+	pieces := chunk(text, x.chunkSize, x.overlap)
+	chunks := make([]types.EmbeddedChunk, 0, len(pieces))
+	for start := 0; start < len(pieces); start += x.batchSize {
+		batch := pieces[start:min(start+x.batchSize, len(pieces))]
+		vectors, err := x.client.embed(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for i, vector := range vectors {
+			chunks = append(chunks, types.EmbeddedChunk{Index: start + i, Content: batch[i], Vector: vector})
+		}
+	}
+	return x.store.SaveEmbeddings(file.ID, x.client.model, chunks)
+}
+
+// Run embeds the files missing from the index, then lets uploads be embedded in the background until ctx is
+// cancelled, when it waits for the files being embedded to give up
+func (x *Index) Run(ctx context.Context) error {
+	x.mu.Lock()
+	x.ctx = ctx
+	x.mu.Unlock()
+
+	x.Backfill(ctx)
+	<-ctx.Done()
+	// IndexInBackground starts nothing once ctx is done, so nothing is added to the wait group past this point
+	x.mu.Lock()
+	x.mu.Unlock()
+	x.background.Wait()
+	return nil
+}
+
+// IndexInBackground embeds the text of an uploaded file without holding up the upload, failures go to the logs table.
+// A file is embedded by one goroutine at a time, text uploaded meanwhile is embedded next and the latest text wins
+func (x *Index) IndexInBackground(file types.FileMetadata, text string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.ctx.Err() != nil {
+		return
+	}
+	if _, busy := x.indexing[file.ID]; busy {
+		x.indexing[file.ID] = &queuedText{file: file, text: text}
+		return
+	}
+	x.indexing[file.ID] = nil
+	x.background.Add(1)
+	ctx := x.ctx
+	go func() {
+		defer x.background.Done()
+		for {
+			x.indexText(ctx, file, text)
+			x.mu.Lock()
+			next := x.indexing[file.ID]
+			if next == nil || ctx.Err() != nil {
+				delete(x.indexing, file.ID)
+				x.mu.Unlock()
+				return
+			}
+			x.indexing[file.ID] = nil
+			x.mu.Unlock()
+			file, text = next.file, next.text
+		}
+	}()
+}
+
+// indexText embeds the text of a file, giving every batch of chunks the request timeout
+func (x *Index) indexText(ctx context.Context, file types.FileMetadata, text string) {
+	batches := 1 + len(text)/((x.chunkSize-x.overlap)*x.batchSize)
+	ctx, cancel := context.WithTimeout(ctx, x.timeout*time.Duration(batches))
+	defer cancel()
+	if err := x.IndexFile(ctx, file, text); err != nil && !errors.Is(err, context.Canceled) {
+		x.logFailure(file, err)
+	}
+}
+
+// Backfill embeds the files that have no chunks for the configured model yet, for files uploaded before semantic
+// search was enabled or while the endpoint was unreachable. It stops when ctx is cancelled
+func (x *Index) Backfill(ctx context.Context) {
+	files, err := x.store.ListFilesWithoutEmbeddings(ctx, x.client.model)
+	if err != nil {
+		slog.Error("Failed to list files to embed", "error", err)
+		return
+	}
+	embedded := 0
+	defer func() {
+		if embedded > 0 {
+			slog.Info("Embedded file text for semantic search", "files", embedded)
+		}
+	}()
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return
+		}
+		text, err := x.store.GetFileText(ctx, file)
+		if err != nil || text == "" {
+			continue
+		}
+		if err := x.IndexFile(ctx, file, text); err != nil {
+			x.logFailure(file, err)
+			// the endpoint is likely down, the next start retries
+			return
+		}
+		embedded++
+	}
+}
+
+func (x *Index) logFailure(file types.FileMetadata, err error) {
+	slog.Error("Failed to embed file text", "filename", file.Filename, "error", err)
+	logErr := x.store.CreateLogEntry(types.LogEntry{
+		Message:    "Failed to embed text of " + file.Filename + ": " + err.Error(),
+		Type:       "error",
+		Action:     "embed",
+		ClientName: "admin",
+	})
+	if logErr != nil {
+		slog.Error("Failed to log embedding error", "error", logErr)
+	}
+}
+
+// Search finds the files the client may read whose text is closest in meaning to the query, with their best matching
+// chunk. An empty client ID searches every file
+func (x *Index) Search(ctx context.Context, clientID, query string, limit int) ([]types.SemanticMatch, error) {
+	if x == nil {
+		return nil, ErrNotConfigured
+	}
+	vectors, err := x.client.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return x.store.SearchEmbeddings(ctx, clientID, x.client.model, vectors[0], limit)
+}
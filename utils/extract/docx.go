@@ -0,0 +1,135 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxPartSize bounds how much of a single XML part of an Office document is read
+const maxPartSize = 64 << 20 // 64 MB
+
+// readPart reads a part of an Office document, a missing part is returned as nil
+func readPart(archive *zip.Reader, name string) ([]byte, error) {
+	for _, f := range archive.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxPartSize))
+	}
+	return nil, nil
+}
+
+// extractDOCX renders the paragraphs of a Word document as Markdown, headings included, and its tables as rows of cells
+func extractDOCX(data []byte) (Rendition, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Rendition{}, err
+	}
+	document, err := readPart(archive, "word/document.xml")
+	if err != nil {
+		return Rendition{}, err
+	}
+	if document == nil {
+		return Rendition{}, fmt.Errorf("not a Word document: word/document.xml is missing")
+	}
+
+	var text, paragraph strings.Builder
+	heading := 0
+	inCell := false
+	// rows and cells of the current table, the first row is its Markdown header
+	rows, cells := 0, 0
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Rendition{}, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "pStyle":
+				heading = headingLevel(attribute(t, "val"))
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			case "tbl":
+				rows = 0
+			case "tr":
+				cells = 0
+			case "tc":
+				inCell = true
+				cells++
+			case "t":
+				var content string
+				if err := decoder.DecodeElement(&content, &t); err != nil {
+					return Rendition{}, err
+				}
+				paragraph.WriteString(content)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				line := strings.TrimSpace(paragraph.String())
+				paragraph.Reset()
+				switch {
+				case inCell:
+					// paragraphs of a cell are joined and closed with the cell
+					paragraph.WriteString(line + " ")
+				case line == "":
+				case heading > 0:
+					fmt.Fprintf(&text, "%s %s\n\n", strings.Repeat("#", heading), line)
+				default:
+					text.WriteString(line + "\n\n")
+				}
+				heading = 0
+			case "tc":
+				text.WriteString("| " + strings.TrimSpace(paragraph.String()) + " ")
+				paragraph.Reset()
+				inCell = false
+			case "tr":
+				text.WriteString("|\n")
+				if rows == 0 {
+					text.WriteString(strings.Repeat("| --- ", cells) + "|\n")
+				}
+				rows++
+			case "tbl":
+				text.WriteString("\n")
+			}
+		}
+	}
+	return Rendition{MimeType: Markdown, Text: text.String()}, nil
+}
+
+// headingLevel maps the Heading1..Heading6 and Title paragraph styles to a Markdown heading level
+func headingLevel(style string) int {
+	if style == "Title" {
+		return 1
+	}
+	if level, ok := strings.CutPrefix(style, "Heading"); ok && len(level) == 1 && level[0] >= '1' && level[0] <= '6' {
+		return int(level[0] - '0')
+	}
+	return 0
+}
+
+// attribute returns the value of an attribute of an element by its local name
+func attribute(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+// Package extract turns uploaded documents into plain-text or Markdown renditions an LLM can read
+package extract
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Rendition MIME types
+const (
+	Markdown  = "text/markdown"
+	PlainText = "text/plain"
+)
+
+// MIME types of the documents renditions are extracted from
+const (
+	PDF  = "application/pdf"
+	DOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	XLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	HTML = "text/html"
+)
+
+// Rendition is the text extracted from a document
+type Rendition struct {
+	MimeType string
+	Text     string
+}
+
+// extractor extracts the text of one kind of document
+type extractor func(data []byte) (Rendition, error)
+
+var extractors = map[string]extractor{
+	PDF:                     extractPDF,
+	DOCX:                    extractDOCX,
+	XLSX:                    extractXLSX,
+	HTML:                    extractHTML,
+	"application/xhtml+xml": extractHTML,
+}
+
+// extensions covers the document types whose extension the standard library may not know
+var extensions = map[string]string{
+	".pdf":   PDF,
+	".docx":  DOCX,
+	".xlsx":  XLSX,
+	".html":  HTML,
+	".htm":   HTML,
+	".xhtml": "application/xhtml+xml",
+	".csv":   "text/csv",
+	".md":    Markdown,
+}
+
+// baseType drops the parameters of a MIME type and lowercases it
+func baseType(mimeType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+}
+
+// DetectMimeType returns the MIME type of an upload. The type declared by the browser is kept unless it is missing
+// or generic, then the filename extension and finally the content decide
+func DetectMimeType(filename, declared string, head []byte) string {
+	switch baseType(declared) {
+	case "", "application/octet-stream", "binary/octet-stream", "application/x-download":
+	default:
+		return declared
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if mimeType, ok := extensions[ext]; ok {
+		return mimeType
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType(head)
+}
+
+// IsText reports whether content of the given MIME type can be served as text
+func IsText(mimeType string) bool {
+	mimeType = baseType(mimeType)
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+	switch mimeType {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml",
+		"application/yaml", "application/toml", "application/x-sh", "image/svg+xml":
+		return true
+	}
+	return strings.HasSuffix(mimeType, "+json") || strings.HasSuffix(mimeType, "+xml")
+}
+
+// Supported reports whether a rendition can be extracted from documents of the given MIME type
+func Supported(mimeType string) bool {
+	_, ok := extractors[baseType(mimeType)]
+	return ok
+}
+
+// Extract extracts the rendition of a document, unsupported MIME types are an error
+func Extract(mimeType string, data []byte) (rendition Rendition, err error) {
+	extract, ok := extractors[baseType(mimeType)]
+	if !ok {
+		return Rendition{}, fmt.Errorf("no text extraction for %s", mimeType)
+	}
+	// the parsers work on untrusted uploads, a malformed one must not bring the server down
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed %s document: %v", mimeType, r)
+		}
+	}()
+	rendition, err = extract(data)
+	if err != nil {
+		return Rendition{}, err
+	}
+	rendition.Text = strings.TrimSpace(rendition.Text) + "\n"
+	return rendition, nil
+}
@@ -0,0 +1,87 @@
+package extract
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractHTML renders the visible text of a page as Markdown, keeping headings, list items and links
+func extractHTML(data []byte) (Rendition, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return Rendition{}, err
+	}
+	var text strings.Builder
+	renderHTML(&text, root)
+
+	// collapse the blank lines block elements leave behind
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text.String(), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return Rendition{MimeType: Markdown, Text: strings.Join(lines, "\n")}, nil
+}
+
+func renderHTML(text *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if words := strings.Fields(n.Data); len(words) > 0 {
+			if strings.TrimLeft(n.Data, " \t\r\n") != n.Data {
+				text.WriteString(" ")
+			}
+			text.WriteString(strings.Join(words, " "))
+			if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+				text.WriteString(" ")
+			}
+		}
+		return
+	case html.CommentNode, html.DoctypeNode:
+		return
+	case html.ElementNode:
+		switch n.Data {
+		case "script", "style", "noscript", "template", "head", "svg":
+			return
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			text.WriteString("\n\n" + strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		case "li":
+			text.WriteString("\n- ")
+		case "br":
+			text.WriteString("\n")
+		case "tr":
+			text.WriteString("\n|")
+		case "p", "div", "section", "article", "header", "footer", "ul", "ol", "table", "blockquote", "pre":
+			text.WriteString("\n\n")
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderHTML(text, c)
+	}
+
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "a":
+			for _, a := range n.Attr {
+				if a.Key == "href" && strings.HasPrefix(a.Val, "http") {
+					text.WriteString(" (" + a.Val + ")")
+				}
+			}
+		case "td", "th":
+			text.WriteString(" |")
+		case "h1", "h2", "h3", "h4", "h5", "h6", "p", "div", "section", "article", "header", "footer", "ul", "ol", "table", "blockquote", "pre":
+			text.WriteString("\n\n")
+		}
+	}
+}
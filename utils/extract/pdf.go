@@ -0,0 +1,38 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// extractPDF renders the text of every page under a Markdown heading with the page number
+func extractPDF(data []byte) (Rendition, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Rendition{}, err
+	}
+
+	var text strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		content, err := page.GetPlainText(fonts)
+		if err != nil {
+			return Rendition{}, fmt.Errorf("page %d: %w", i, err)
+		}
+		fmt.Fprintf(&text, "## Page %d\n\n%s\n\n", i, strings.TrimSpace(content))
+	}
+	return Rendition{MimeType: Markdown, Text: text.String()}, nil
+}
@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxColumns is the number of columns a sheet can have, A to XFD
+	maxColumns = 16384
+	// maxTableColumns caps the width of the Markdown table of a sheet, every row is padded to it
+	maxTableColumns = 256
+)
+
+// workbook lists the sheets of a spreadsheet in order
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// relationships maps the relationship IDs of the workbook to its parts
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// sharedStrings holds the strings cells refer to by index
+type sharedStrings struct {
+	Items []richText `xml:"si"`
+}
+
+// richText is a string made of one plain or several formatted runs
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r richText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var s strings.Builder
+	for _, run := range r.Runs {
+		s.WriteString(run.Text)
+	}
+	return s.String()
+}
+
+// worksheet holds the rows of one sheet
+type worksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline richText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// extractXLSX renders every sheet of a workbook as a Markdown table under a heading with the sheet name
+func extractXLSX(data []byte) (Rendition, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Rendition{}, err
+	}
+	var book workbook
+	if err := decodePart(archive, "xl/workbook.xml", &book); err != nil {
+		return Rendition{}, err
+	}
+	var rels relationships
+	if err := decodePart(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return Rendition{}, err
+	}
+	var shared sharedStrings
+	if err := decodePart(archive, "xl/sharedStrings.xml", &shared); err != nil {
+		return Rendition{}, err
+	}
+
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var text strings.Builder
+	for _, sheet := range book.Sheets {
+		var ws worksheet
+		if err := decodePart(archive, targets[sheet.RID], &ws); err != nil {
+			return Rendition{}, fmt.Errorf("sheet %s: %w", sheet.Name, err)
+		}
+		fmt.Fprintf(&text, "## %s\n\n", sheet.Name)
+
+		rows := make([][]string, 0, len(ws.Rows))
+		width := 0
+		cut := false
+		for _, row := range ws.Rows {
+			var cells []string
+			for i, cell := range row.Cells {
+				column, err := columnIndex(cell.Ref)
+				if err != nil {
+					return Rendition{}, fmt.Errorf("sheet %s: %w", sheet.Name, err)
+				}
+				if column < 0 {
+					column = i
+				}
+				if column >= maxTableColumns {
+					cut = true
+					continue
+				}
+				for len(cells) < column {
+					cells = append(cells, "")
+				}
+				value := cell.Value
+				switch cell.Type {
+				case "s":
+					if index, err := strconv.Atoi(value); err == nil && index >= 0 && index < len(shared.Items) {
+						value = shared.Items[index].String()
+					}
+				case "inlineStr":
+					value = cell.Inline.String()
+				}
+				cells = append(cells, markdownCell(value))
+			}
+			rows = append(rows, cells)
+			width = max(width, len(cells))
+		}
+		for i, cells := range rows {
+			for len(cells) < width {
+				cells = append(cells, "")
+			}
+			text.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+			if i == 0 {
+				text.WriteString(strings.Repeat("| --- ", width) + "|\n")
+			}
+		}
+		if cut {
+			fmt.Fprintf(&text, "\n*Columns past %d are left out.*\n", maxTableColumns)
+		}
+		text.WriteString("\n")
+	}
+	return Rendition{MimeType: Markdown, Text: text.String()}, nil
+}
+
+// decodePart unmarshals an XML part of an Office document, a missing part leaves v empty
+func decodePart(archive *zip.Reader, name string, v any) error {
+	data, err := readPart(archive, name)
+	if err != nil || data == nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// columnIndex returns the zero-based column of a cell reference such as "C7", or -1 when the reference has no column
+func columnIndex(ref string) (int, error) {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		if column > maxColumns {
+			return 0, fmt.Errorf("cell %s is past the last column XFD", ref)
+		}
+	}
+	return column - 1, nil
+}
+
+// markdownCell keeps a cell value on one line of a Markdown table
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.Join(strings.Fields(value), " ")
+}
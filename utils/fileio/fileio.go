@@ -0,0 +1,82 @@
+// Package fileio reads stored files block by block, giving up as soon as the context of the request reading them is
+// cancelled
+package fileio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// blockSize is how much is read between two checks of the context
+const blockSize = 64 << 10 // 64 KB
+
+// reader reads from r, failing with the error of ctx once it is done
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// NewReader returns a reader of r that stops with the error of ctx once it is cancelled
+func NewReader(ctx context.Context, r io.Reader) io.Reader {
+	return &reader{ctx: ctx, r: r}
+}
+
+// ReadAll reads a whole file, like os.ReadFile
+func ReadAll(ctx context.Context, path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(NewReader(ctx, f))
+}
+
+// ReadRange reads up to length bytes of a file starting at offset, returning them with the size of the file.
+// progress, when not nil, is told how many bytes were read after every block
+func ReadRange(ctx context.Context, path string, offset, length int64, progress func(read int64)) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset >= info.Size() {
+		return []byte{}, info.Size(), nil
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	data := make([]byte, min(length, info.Size()-offset))
+	read := 0
+	for read < len(data) {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		n, err := io.ReadFull(f, data[read:min(read+blockSize, len(data))])
+		read += n
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// the file shrank since it was opened
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if progress != nil {
+			progress(int64(read))
+		}
+	}
+	return data[:read], info.Size(), nil
+}
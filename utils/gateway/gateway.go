@@ -0,0 +1,367 @@
+// Package gateway connects to downstream MCP servers and re-exports their tools, resources and prompts under a namespace prefix
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// Separator joins a downstream server name and the name of one of its tools or prompts
+	Separator = "__"
+	// resourcePrefix is the URI prefix under which downstream resources are re-exported
+	resourcePrefix = "panes://gateway/"
+	// connectTimeout bounds the handshake with a downstream server and the listing of what it offers
+	connectTimeout = 30 * time.Second
+)
+
+// inheritedEnv are the variables of Panes' environment every stdio server gets, everything else it needs is declared
+// under env so that the admin key and API secrets of Panes do not leak to third-party servers
+var inheritedEnv = []string{"PATH", "HOME"}
+
+// validName allows letters, digits, - and single _ between them, never the separator nor an _ that could run into it,
+// so that server__tool names of two servers can never collide
+var validName = regexp.MustCompile(`^[A-Za-z0-9-]+(_[A-Za-z0-9-]+)*$`)
+
+// Gateway holds the connections to the downstream servers that could be reached
+type Gateway struct {
+	downstreams []*downstream
+}
+
+// downstream is one connected server and what it offered when Panes connected
+type downstream struct {
+	name      string
+	client    *client.Client
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	prompts   []mcp.Prompt
+}
+
+// Connect launches or connects to every declared downstream server. A server that cannot be reached is reported
+// in the returned errors and left out, so one broken server does not keep Panes from serving the others
+func Connect(ctx context.Context, declared []config.DownstreamServerConfig) (*Gateway, []error) {
+	g := &Gateway{}
+	var errs []error
+	seen := make(map[string]bool)
+	for _, cfg := range declared {
+		if !validName.MatchString(cfg.Name) || seen[cfg.Name] {
+			errs = append(errs, fmt.Errorf("downstream server %q: name must be unique, use only letters, digits, - and single _ and not end with _", cfg.Name))
+			continue
+		}
+		seen[cfg.Name] = true
+
+		d, err := connect(ctx, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("downstream server %s: %w", cfg.Name, err))
+			continue
+		}
+		slog.Info("Connected downstream MCP server", "server", d.name, "tools", len(d.tools), "resources", len(d.resources), "prompts", len(d.prompts))
+		g.downstreams = append(g.downstreams, d)
+	}
+	return g, errs
+}
+
+func connect(ctx context.Context, cfg config.DownstreamServerConfig) (*downstream, error) {
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := &downstream{name: cfg.Name, client: c}
+	if err := d.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// newClient creates the MCP client of a downstream server over its configured transport
+func newClient(cfg config.DownstreamServerConfig) (*client.Client, error) {
+	transportName := cfg.Transport
+	if transportName == "" {
+		transportName = config.DownstreamStreamableHTTP
+		if cfg.Command != "" {
+			transportName = config.DownstreamStdio
+		}
+	}
+
+	switch transportName {
+	case config.DownstreamStdio:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("command is required for the stdio transport")
+		}
+		var env []string
+		for _, name := range inheritedEnv {
+			if value, ok := os.LookupEnv(name); ok {
+				env = append(env, name+"="+value)
+			}
+		}
+		for name, value := range cfg.Env {
+			expanded, err := config.ExpandEnv(value)
+			if err != nil {
+				return nil, fmt.Errorf("env %s: %w", name, err)
+			}
+			env = append(env, name+"="+expanded)
+		}
+		c, err := client.NewStdioMCPClientWithOptions(cfg.Command, env, cfg.Args, transport.WithCommandFunc(command))
+		if err != nil {
+			return nil, err
+		}
+		if stderr, ok := client.GetStderr(c); ok {
+			go logStderr(cfg.Name, stderr)
+		}
+		return c, nil
+
+	case config.DownstreamStreamableHTTP, config.DownstreamSSE:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("url is required for the %s transport", transportName)
+		}
+		headers := make(map[string]string, len(cfg.Headers))
+		for name, value := range cfg.Headers {
+			expanded, err := config.ExpandEnv(value)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %w", name, err)
+			}
+			headers[name] = expanded
+		}
+		if transportName == config.DownstreamSSE {
+			return client.NewSSEMCPClient(cfg.URL, transport.WithHeaders(headers))
+		}
+		return client.NewStreamableHttpClient(cfg.URL, transport.WithHTTPHeaders(headers))
+	}
+	return nil, fmt.Errorf("unknown transport %q", transportName)
+}
+
+// command launches a stdio server with exactly the given environment, mcp-go would add all of Panes' to it
+func command(ctx context.Context, name string, env []string, args []string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = env
+	return cmd, nil
+}
+
+// logStderr forwards what a downstream subprocess writes to stderr into Panes' log
+func logStderr(name string, stderr interface{ Read([]byte) (int, error) }) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		slog.Info("Downstream MCP server output", "server", name, "line", scanner.Text())
+	}
+}
+
+// initialize performs the MCP handshake and snapshots the tools, resources and prompts the server offers
+func (d *downstream) initialize(ctx context.Context) error {
+	// the transport keeps its streams open for as long as the context it was started with
+	if err := d.client.Start(ctx); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	request := mcp.InitializeRequest{}
+	request.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	request.Params.ClientInfo = mcp.Implementation{Name: "Panes gateway", Version: "1.0.0"}
+	result, err := d.client.Initialize(ctx, request)
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+
+	// downstream servers may paginate their lists, every page is followed
+	if result.Capabilities.Tools != nil {
+		request := mcp.ListToolsRequest{}
+		for {
+			page, err := d.client.ListTools(ctx, request)
+			if err != nil {
+				return fmt.Errorf("list tools: %w", err)
+			}
+			d.tools = append(d.tools, page.Tools...)
+			if page.NextCursor == "" {
+				break
+			}
+			request.Params.Cursor = page.NextCursor
+		}
+	}
+	if result.Capabilities.Resources != nil {
+		request := mcp.ListResourcesRequest{}
+		for {
+			page, err := d.client.ListResources(ctx, request)
+			if err != nil {
+				return fmt.Errorf("list resources: %w", err)
+			}
+			d.resources = append(d.resources, page.Resources...)
+			if page.NextCursor == "" {
+				break
+			}
+			request.Params.Cursor = page.NextCursor
+		}
+	}
+	if result.Capabilities.Prompts != nil {
+		request := mcp.ListPromptsRequest{}
+		for {
+			page, err := d.client.ListPrompts(ctx, request)
+			if err != nil {
+				return fmt.Errorf("list prompts: %w", err)
+			}
+			d.prompts = append(d.prompts, page.Prompts...)
+			if page.NextCursor == "" {
+				break
+			}
+			request.Params.Cursor = page.NextCursor
+		}
+	}
+	return nil
+}
+
+// Close disconnects from every downstream server, stopping the subprocesses Panes launched
+func (g *Gateway) Close() {
+	for _, d := range g.downstreams {
+		if err := d.client.Close(); err != nil {
+			slog.Error("Failed to close downstream MCP server", "server", d.name, "error", err)
+		}
+	}
+}
+
+// ResourceURI is the URI a downstream resource is re-exported under
+func ResourceURI(server, uri string) string {
+	return resourcePrefix + server + "/" + url.PathEscape(uri)
+}
+
+// ServerOfResource returns the downstream server a re-exported resource URI belongs to, and the URI on that server
+func ServerOfResource(uri string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(uri, resourcePrefix)
+	if !ok {
+		return "", "", false
+	}
+	server, escaped, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", "", false
+	}
+	original, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", "", false
+	}
+	return server, original, true
+}
+
+// ServerOfName returns the downstream server a namespaced tool or prompt name belongs to, and its name on that server
+func ServerOfName(name string) (string, string, bool) {
+	return strings.Cut(name, Separator)
+}
+
+// Tools returns the downstream tools, namespaced and proxied to their server
+func (g *Gateway) Tools() []server.ServerTool {
+	var tools []server.ServerTool
+	for _, d := range g.downstreams {
+		for _, tool := range d.tools {
+			original := tool.Name
+			tool.Name = d.name + Separator + original
+			tool.Description = fmt.Sprintf("[%s] %s", d.name, tool.Description)
+			tools = append(tools, server.ServerTool{Tool: tool, Handler: d.callTool(original)})
+		}
+	}
+	return tools
+}
+
+func (d *downstream) callTool(original string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		forwarded := mcp.CallToolRequest{}
+		forwarded.Params.Name = original
+		forwarded.Params.Arguments = request.Params.Arguments
+		forwarded.Params.Meta = request.Params.Meta
+		result, err := d.client.CallTool(ctx, forwarded)
+		if err != nil {
+			slog.Error("Downstream tool call failed", "server", d.name, "tool", original, "error", err)
+			return mcp.NewToolResultErrorf("%s: %v", d.name, err), nil
+		}
+		return result, nil
+	}
+}
+
+// Resources returns the downstream resources, re-exported under panes://gateway/<server>/ and proxied to their server
+func (g *Gateway) Resources() []server.ServerResource {
+	var resources []server.ServerResource
+	for _, d := range g.downstreams {
+		for _, resource := range d.resources {
+			resource.URI = ResourceURI(d.name, resource.URI)
+			resource.Name = d.name + Separator + resource.Name
+			resources = append(resources, server.ServerResource{Resource: resource, Handler: d.readResource})
+		}
+	}
+	return resources
+}
+
+func (d *downstream) readResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	_, original, ok := ServerOfResource(request.Params.URI)
+	if !ok {
+		return nil, fmt.Errorf("not a gateway resource: %s", request.Params.URI)
+	}
+	forwarded := mcp.ReadResourceRequest{}
+	forwarded.Params.URI = original
+	result, err := d.client.ReadResource(ctx, forwarded)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", d.name, err)
+	}
+
+	// contents keep pointing at the re-exported URI
+	contents := make([]mcp.ResourceContents, 0, len(result.Contents))
+	for _, content := range result.Contents {
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			c.URI = ResourceURI(d.name, c.URI)
+			content = c
+		case mcp.BlobResourceContents:
+			c.URI = ResourceURI(d.name, c.URI)
+			content = c
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
+// Prompts returns the downstream prompts, namespaced and proxied to their server
+func (g *Gateway) Prompts() []server.ServerPrompt {
+	var prompts []server.ServerPrompt
+	for _, d := range g.downstreams {
+		for _, prompt := range d.prompts {
+			original := prompt.Name
+			prompt.Name = d.name + Separator + original
+			prompts = append(prompts, server.ServerPrompt{Prompt: prompt, Handler: d.getPrompt(original)})
+		}
+	}
+	return prompts
+}
+
+func (d *downstream) getPrompt(original string) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		forwarded := mcp.GetPromptRequest{}
+		forwarded.Params.Name = original
+		forwarded.Params.Arguments = request.Params.Arguments
+		result, err := d.client.GetPrompt(ctx, forwarded)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.name, err)
+		}
+		return result, nil
+	}
+}
+
+// Servers lists the names of the connected downstream servers, sorted
+func (g *Gateway) Servers() []string {
+	names := make([]string, 0, len(g.downstreams))
+	for _, d := range g.downstreams {
+		names = append(names, d.name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// audit records every initialize, tool call, resource read and prompt get into the logs table, throttled calls as warnings
+type audit struct {
+	store   *storage.SQLite
+	started sync.Map // request key -> time.Time
+}
+
+func newAudit(store *storage.SQLite) *audit {
+	return &audit{store: store}
+}
+
+// register adds the audit hooks to the MCP server hooks
+func (a *audit) register(hooks *server.Hooks) {
+	hooks.AddBeforeAny(a.before)
+	hooks.AddOnSuccess(func(ctx context.Context, id any, method mcp.MCPMethod, message any, result any) {
+		var err error
+		if result, ok := result.(*mcp.CallToolResult); ok && result.IsError {
+			err = toolError(result)
+		}
+		a.after(ctx, id, method, message, err)
+	})
+	hooks.AddOnError(a.after)
+}
+
+// audited reports whether requests of a method are recorded
+func audited(method mcp.MCPMethod) bool {
+	switch method {
+	case mcp.MethodInitialize, mcp.MethodToolsCall, mcp.MethodResourcesRead, mcp.MethodPromptsGet:
+		return true
+	}
+	return false
+}
+
+// requestKey identifies a request across sessions, whose JSON-RPC IDs may collide
+func requestKey(ctx context.Context, id any) string {
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("%s/%v", sessionID, id)
+}
+
+func (a *audit) before(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+	if audited(method) {
+		a.started.Store(requestKey(ctx, id), time.Now())
+	}
+}
+
+func (a *audit) after(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+	if !audited(method) {
+		return
+	}
+	var duration time.Duration
+	if started, ok := a.started.LoadAndDelete(requestKey(ctx, id)); ok {
+		duration = time.Since(started.(time.Time))
+	}
+
+	target := auditTarget(message)
+	log := types.LogEntry{
+		Type:       "success",
+		Action:     string(method),
+		ClientName: clientName(ctx, message),
+		Target:     target,
+		DurationMs: duration.Milliseconds(),
+		Message:    fmt.Sprintf("MCP %s %s succeeded in %s", method, target, duration.Round(time.Millisecond)),
+	}
+	var refusal *throttled
+	if errors.As(err, &refusal) {
+		log.Type = "warning"
+		log.Message = fmt.Sprintf("MCP %s %s throttled: %s", method, target, refusal)
+	} else if err != nil {
+		log.Type = "error"
+		log.Message = fmt.Sprintf("MCP %s %s failed in %s: %s", method, target, duration.Round(time.Millisecond), err)
+	}
+	if err := a.store.CreateLogEntry(log); err != nil {
+		slog.Error("Failed to audit MCP request", "method", method, "error", err)
+	}
+}
+
+// auditTarget names what a request was about: the tool, resource URI or prompt, or the client software on initialize
+func auditTarget(message any) string {
+	switch request := message.(type) {
+	case *mcp.InitializeRequest:
+		return request.Params.ClientInfo.Name + " " + request.Params.ClientInfo.Version
+	case *mcp.CallToolRequest:
+		return request.Params.Name
+	case *mcp.ReadResourceRequest:
+		return request.Params.URI
+	case *mcp.GetPromptRequest:
+		return request.Params.Name
+	}
+	return ""
+}
+
+// clientName is the name of the authenticated client, or the name the client gave on initialize for the local stdio transport
+func clientName(ctx context.Context, message any) string {
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		return client.ClientName
+	}
+	if request, ok := message.(*mcp.InitializeRequest); ok && request.Params.ClientInfo.Name != "" {
+		return request.Params.ClientInfo.Name
+	}
+	if session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo); ok {
+		if name := session.GetClientInfo().Name; name != "" {
+			return name
+		}
+	}
+	return stdioSessionID
+}
+
+// toolError turns the text of a failed tool result into an error
+func toolError(result *mcp.CallToolResult) error {
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			return fmt.Errorf("%s", text.Text)
+		}
+	}
+	return fmt.Errorf("tool returned an error")
+}
@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sessionOwners remembers which client opened each HTTP session, so a session ID cannot be used with another client's key
+type sessionOwners struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func newSessionOwners() *sessionOwners {
+	return &sessionOwners{owners: make(map[string]string)}
+}
+
+func (o *sessionOwners) register(ctx context.Context, session server.ClientSession) {
+	client, ok := auth.ClientFromContext(ctx)
+	if !ok {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.owners[session.SessionID()] = client.ClientID
+}
+
+func (o *sessionOwners) unregister(ctx context.Context, session server.ClientSession) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.owners, session.SessionID())
+}
+
+// owns reports whether the session is unknown or was opened by the given client
+func (o *sessionOwners) owns(sessionID, clientID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	owner, ok := o.owners[sessionID]
+	return !ok || owner == clientID
+}
+
+// authenticated requires a valid client API key on every request of an HTTP transport
+// and refuses requests for sessions opened by another client
+func (s *Server) authenticated(next http.Handler, sessionID func(r *http.Request) string) http.Handler {
+	return auth.Middleware(s.store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _ := auth.ClientFromContext(r.Context())
+		if id := sessionID(r); id != "" && !s.sessionOwners.owns(id, client.ClientID) {
+			slog.Warn("Rejected MCP request for another client's session", "client", client.ClientID, "session", id)
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func streamableSessionID(r *http.Request) string {
+	return r.Header.Get(server.HeaderKeySessionID)
+}
+
+func sseSessionID(r *http.Request) string {
+	return r.URL.Query().Get("sessionId")
+}
@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// methodCancelled is the notification a client sends to give up on one of its requests
+const methodCancelled = "notifications/cancelled"
+
+// inflight tracks the tool calls being answered so notifications/cancelled can cancel their context.
+// mcp-go gives the JSON-RPC ID of a call to hooks but not to tool handlers: the before hook ties the ID to the _meta
+// of the request, which the handler gets a copy of the same pointer to
+type inflight struct {
+	mu      sync.Mutex
+	pending map[*mcp.Meta]string          // _meta of a call about to be handled -> its request key
+	calls   map[string]context.CancelFunc // request key -> cancels the call
+}
+
+func newInflight() *inflight {
+	return &inflight{pending: make(map[*mcp.Meta]string), calls: make(map[string]context.CancelFunc)}
+}
+
+// before remembers which request a tool call is, just before mcp-go hands it to its handler
+func (f *inflight) before(ctx context.Context, id any, message *mcp.CallToolRequest) {
+	if message.Params.Meta == nil {
+		message.Params.Meta = &mcp.Meta{}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// hooks get the ID as decoded JSON, a float64 or a string, keyed like the requestId of notifications/cancelled
+	f.pending[message.Params.Meta] = requestKey(ctx, mcp.NewRequestId(id))
+}
+
+// toolMiddleware answers tool calls with a context cancelled by notifications/cancelled, reporting progress under
+// the token the client sent with the call
+func (f *inflight) toolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		if meta := request.Params.Meta; meta != nil {
+			ctx = provider.WithProgressToken(ctx, meta.ProgressToken)
+			f.mu.Lock()
+			key, ok := f.pending[meta]
+			if ok {
+				f.calls[key] = cancel
+			}
+			f.mu.Unlock()
+			if ok {
+				defer f.done(key)
+			}
+		}
+		return next(ctx, request)
+	}
+}
+
+// after forgets a tool call once it was answered, whether or not it reached its handler
+func (f *inflight) after(ctx context.Context, id any, message *mcp.CallToolRequest, result any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.pending, message.Params.Meta)
+}
+
+// failed forgets a tool call mcp-go answered with an error, such as a call to an unknown tool
+func (f *inflight) failed(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
+	if request, ok := message.(*mcp.CallToolRequest); ok {
+		f.after(ctx, id, request, nil)
+	}
+}
+
+func (f *inflight) done(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.calls, key)
+}
+
+// cancelled cancels the tool call a client gave up on, calls that already ended are ignored
+func (f *inflight) cancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	id, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	key := requestKey(ctx, mcp.NewRequestId(id))
+	f.mu.Lock()
+	cancel, ok := f.calls[key]
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+	cancel()
+	reason, _ := notification.Params.AdditionalFields["reason"].(string)
+	slog.Info("MCP tool call cancelled by the client", "request", key, "reason", reason)
+}
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newCancellableServer serves one tool answered by handler, with tool calls tracked like NewServer does
+func newCancellableServer(handler server.ToolHandlerFunc) (*server.MCPServer, *inflight) {
+	calls := newInflight()
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(calls.before)
+	hooks.AddAfterCallTool(calls.after)
+	hooks.AddOnError(calls.failed)
+	s := server.NewMCPServer("test", "1.0", server.WithHooks(hooks), server.WithToolHandlerMiddleware(calls.toolMiddleware))
+	s.AddNotificationHandler(methodCancelled, calls.cancelled)
+	s.AddTool(mcp.NewTool("wait"), handler)
+	return s, calls
+}
+
+func handle(t *testing.T, s *server.MCPServer, message string) mcp.JSONRPCMessage {
+	t.Helper()
+	return s.HandleMessage(context.Background(), json.RawMessage(message))
+}
+
+// inflight relies on mcp-go handing the tool handler the very *mcp.Meta the before hook saw, this pins it
+func TestCallToolHooksShareMeta(t *testing.T) {
+	var hooked, handled *mcp.Meta
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest) {
+		if message.Params.Meta == nil {
+			message.Params.Meta = &mcp.Meta{}
+		}
+		hooked = message.Params.Meta
+	})
+	s := server.NewMCPServer("test", "1.0", server.WithHooks(hooks))
+	s.AddTool(mcp.NewTool("wait"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		handled = request.Params.Meta
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	for _, params := range []string{`{"name":"wait"}`, `{"name":"wait","_meta":{"progressToken":"p1"}}`} {
+		hooked, handled = nil, nil
+		handle(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":`+params+`}`)
+		if hooked == nil || handled != hooked {
+			t.Errorf("params %s: the handler got _meta %p, the before hook %p, want the same pointer", params, handled, hooked)
+		}
+	}
+}
+
+func TestCancelledNotificationCancelsToolCall(t *testing.T) {
+	started := make(chan struct{})
+	s, calls := newCancellableServer(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return mcp.NewToolResultError("cancelled"), nil
+		case <-time.After(5 * time.Second):
+			return mcp.NewToolResultText("done"), nil
+		}
+	})
+
+	answered := make(chan mcp.JSONRPCMessage)
+	go func() {
+		answered <- handle(t, s, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`)
+	}()
+	<-started
+	handle(t, s, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user gave up"}}`)
+
+	select {
+	case message := <-answered:
+		response, ok := message.(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("answer is %T, want a response", message)
+		}
+		if result, ok := response.Result.(*mcp.CallToolResult); !ok || !result.IsError {
+			t.Errorf("result = %+v, want the error of the cancelled handler", response.Result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the tool call was not cancelled")
+	}
+
+	calls.mu.Lock()
+	defer calls.mu.Unlock()
+	if len(calls.pending) != 0 || len(calls.calls) != 0 {
+		t.Errorf("%d pending and %d running calls are left after the answer", len(calls.pending), len(calls.calls))
+	}
+}
+
+func TestCancelledNotificationForOtherRequest(t *testing.T) {
+	var s *server.MCPServer
+	s, _ = newCancellableServer(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		handle(t, s, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":8}}`)
+		if ctx.Err() != nil {
+			return mcp.NewToolResultError("cancelled"), nil
+		}
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	response, ok := handle(t, s, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("the tool call was not answered")
+	}
+	if result, ok := response.Result.(*mcp.CallToolResult); !ok || result.IsError {
+		t.Errorf("result = %+v, want the call to go on when another request is cancelled", response.Result)
+	}
+}
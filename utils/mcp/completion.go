@@ -0,0 +1,37 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxCompletionValues is the most values a completion result may carry
+const maxCompletionValues = 100
+
+// fileCompletions completes the filename argument of the file resource template from the files the client may read
+type fileCompletions struct {
+	store *storage.SQLite
+}
+
+func (c *fileCompletions) CompleteResourceArgument(ctx context.Context, uri string, argument mcp.CompleteArgument, context mcp.CompleteContext) (*mcp.Completion, error) {
+	if uri != fileResourceTemplate || argument.Name != "filename" {
+		return &mcp.Completion{Values: []string{}}, nil
+	}
+
+	// ask for one more than we can send to know whether there are more matches
+	files, err := c.store.SearchFilesByName(ctx, callerFromContext(ctx).ClientID, argument.Value, maxCompletionValues+1)
+	if err != nil {
+		return nil, err
+	}
+	completion := &mcp.Completion{Values: []string{}}
+	for i, file := range files {
+		if i == maxCompletionValues {
+			completion.HasMore = true
+			break
+		}
+		completion.Values = append(completion.Values, file.Filename)
+	}
+	return completion, nil
+}
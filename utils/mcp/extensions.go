@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// stdioSessionID is the session ID mcp-go gives to the single stdio client
+const stdioSessionID = "stdio"
+
+// extensionHandler answers a JSON-RPC request whose method mcp-go does not dispatch itself
+type extensionHandler func(ctx context.Context, sessionID string, message json.RawMessage) (any, error)
+
+// internalError is an extension failure that is not the client's fault, answered as an internal error without the
+// details of err. Other failures are answered as invalid params
+type internalError struct {
+	message string
+	err     error
+}
+
+func (e *internalError) Error() string {
+	return e.message + ": " + e.err.Error()
+}
+
+// extensions answers the methods Panes implements on top of mcp-go, in front of every transport
+type extensions struct {
+	server   *server.MCPServer
+	handlers map[string]extensionHandler
+	// observe is told about every request of an established session
+	observe func(sessionID, method string)
+}
+
+func newExtensions(s *server.MCPServer) *extensions {
+	return &extensions{server: s, handlers: make(map[string]extensionHandler)}
+}
+
+func (e *extensions) add(method string, handler extensionHandler) {
+	e.handlers[method] = handler
+}
+
+// handle answers the message if its method is an extension, reporting whether it did
+func (e *extensions) handle(ctx context.Context, sessionID string, message []byte) (mcp.JSONRPCMessage, bool) {
+	var request struct {
+		ID     any    `json:"id"`
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(message, &request); err != nil || request.ID == nil {
+		return nil, false
+	}
+	if e.observe != nil {
+		e.observe(sessionID, request.Method)
+	}
+	handler, ok := e.handlers[request.Method]
+	if !ok {
+		return nil, false
+	}
+	ctx = withLogSession(ctx, e.server, sessionID)
+
+	result, err := handler(ctx, sessionID, message)
+	if err != nil {
+		slog.Error("MCP extension request failed", "method", request.Method, "session", sessionID, "error", err)
+		var internal *internalError
+		if errors.As(err, &internal) {
+			return mcp.NewJSONRPCError(mcp.NewRequestId(request.ID), mcp.INTERNAL_ERROR, internal.message, nil), true
+		}
+		return mcp.NewJSONRPCError(mcp.NewRequestId(request.ID), mcp.INVALID_PARAMS, err.Error(), nil), true
+	}
+	return mcp.JSONRPCResponse{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		ID:      mcp.NewRequestId(request.ID),
+		Result:  result,
+	}, true
+}
+
+// readBody reads the request body and puts it back so the next handler can read it again
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// streamableHTTP answers extension requests posted to the Streamable HTTP endpoint and passes everything else on
+func (e *extensions) streamableHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(w, "Could not read request body", http.StatusBadRequest)
+			return
+		}
+		sessionID := r.Header.Get(server.HeaderKeySessionID)
+		if sessionID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		response, ok := e.handle(r.Context(), sessionID, body)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// sseMessage answers extension requests posted to the SSE message endpoint over the session's event stream
+func (e *extensions) sseMessage(sse *server.SSEServer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(w, "Could not read request body", http.StatusBadRequest)
+			return
+		}
+		sessionID := r.URL.Query().Get("sessionId")
+		if sessionID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		response, ok := e.handle(r.Context(), sessionID, body)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := sse.SendEventToSession(sessionID, response); err != nil {
+			http.Error(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// lockedWriter serialises whole-message writes of the stdio server and the extensions
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// stdio answers extension requests read from stdin and forwards every other line to the returned reader
+func (e *extensions) stdio(ctx context.Context, stdin io.Reader, stdout io.Writer) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		reader := bufio.NewReader(stdin)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				if response, ok := e.handle(ctx, stdioSessionID, line); ok {
+					data, merr := json.Marshal(response)
+					if merr == nil {
+						stdout.Write(append(data, '\n'))
+					}
+				} else if _, werr := pw.Write(line); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
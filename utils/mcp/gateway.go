@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/gateway"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerGateway re-exports what the downstream servers offer. Their tools go through the tool permissions like any
+// other tool, their resources and prompts need a read grant on the gateway resource kind for the downstream server name.
+// A downstream tool named like a tool already registered is refused rather than replacing it
+func (p *Server) registerGateway(gw *gateway.Gateway, hooks *server.Hooks) error {
+	if gw == nil {
+		return nil
+	}
+	s, perms := p.MCP, p.permissions
+	tools := gw.Tools()
+	for _, tool := range tools {
+		if s.GetTool(tool.Tool.Name) != nil {
+			return fmt.Errorf("downstream tool %s is named like a tool Panes already serves", tool.Tool.Name)
+		}
+	}
+	if len(tools) > 0 {
+		s.AddTools(tools...)
+	}
+	resources := gw.Resources()
+	for _, resource := range resources {
+		p.gatewayResources = append(p.gatewayResources, resource.Resource)
+	}
+	// resources/list pages through them after the files, in URI order
+	slices.SortFunc(p.gatewayResources, func(a, b mcp.Resource) int { return strings.Compare(a.URI, b.URI) })
+	if len(resources) > 0 {
+		s.AddResources(resources...)
+	}
+
+	prompts := gw.Prompts()
+	for i, prompt := range prompts {
+		prompts[i].Handler = perms.gatewayPromptMiddleware(prompt.Handler)
+	}
+	if len(prompts) > 0 {
+		s.AddPrompts(prompts...)
+	}
+	hooks.AddAfterListPrompts(func(ctx context.Context, id any, message *mcp.ListPromptsRequest, result *mcp.ListPromptsResult) {
+		perms.filterPrompts(ctx, result)
+	})
+	return nil
+}
+
+// gatewayPromptMiddleware refuses gets of downstream prompts the client was not granted, answering as if the prompt did not exist
+func (p *permissions) gatewayPromptMiddleware(next server.PromptHandlerFunc) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		downstream, _, _ := gateway.ServerOfName(request.Params.Name)
+		if !p.allowed(ctx, resourceGateway, actionRead, downstream) {
+			return nil, fmt.Errorf("prompt not found: %s", request.Params.Name)
+		}
+		return next(ctx, request)
+	}
+}
+
+// filterPrompts drops the downstream prompts the client was not granted from a prompts/list result
+func (p *permissions) filterPrompts(ctx context.Context, result *mcp.ListPromptsResult) {
+	if _, ok := auth.ClientFromContext(ctx); !ok {
+		return
+	}
+	allowed := []mcp.Prompt{}
+	for _, prompt := range result.Prompts {
+		downstream, _, ok := gateway.ServerOfName(prompt.Name)
+		if !ok || p.allowed(ctx, resourceGateway, actionRead, downstream) {
+			allowed = append(allowed, prompt)
+		}
+	}
+	result.Prompts = allowed
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// logSessionKey carries the session of a request answered by the extensions, which mcp-go did not dispatch
+type logSessionKey struct{}
+
+type logSession struct {
+	server    *server.MCPServer
+	sessionID string
+}
+
+func withLogSession(ctx context.Context, s *server.MCPServer, sessionID string) context.Context {
+	return context.WithValue(ctx, logSessionKey{}, logSession{server: s, sessionID: sessionID})
+}
+
+// notifyClient sends a log message to the session of the request behind ctx, if it asked for messages of that level
+func notifyClient(ctx context.Context, level mcp.LoggingLevel, event, message string, args ...any) {
+	session, ok := ctx.Value(logSessionKey{}).(logSession)
+	if !ok {
+		provider.Notify(ctx, level, event, message, args...)
+		return
+	}
+	err := session.server.SendLogMessageToSpecificClient(session.sessionID, provider.LogMessage(level, event, message, args...))
+	if err != nil {
+		slog.Debug("Could not send log message to MCP client", "session", session.sessionID, "event", event, "error", err)
+	}
+}
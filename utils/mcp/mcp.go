@@ -2,43 +2,178 @@ package mcp
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/gateway"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func Server() {
+// Server is the Panes MCP server together with the state shared by all of its transports
+type Server struct {
+	MCP           *server.MCPServer
+	store         *storage.SQLite
+	files         *fileResources
+	prompts       *promptLibrary
+	subscriptions *subscriptions
+	extensions    *extensions
+	sessionOwners *sessionOwners
+	sessions      *sessions
+	permissions   *permissions
+	limits        *rateLimits
+	// gatewayResources are the re-exported downstream resources, sorted by URI
+	gatewayResources []mcp.Resource
+	pageSize         int
+}
+
+// NewServer builds the Panes MCP server on top of the given storage with the configured tool providers and, when gw is
+// not nil, what the downstream servers of the gateway offer, ready to be served over any transport
+func NewServer(store *storage.SQLite, cfg config.MCPServerConfig, gw *gateway.Gateway) (*Server, error) {
+	hooks := &server.Hooks{}
+	perms := &permissions{store: store}
+	calls := newInflight()
+	// Tool calls and resource reads count against the rate limits and daily quotas of the client
+	limits := newRateLimits(store)
+	pageSize := cfg.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
 	// Create a new MCP server
 	s := server.NewMCPServer(
-		"Hello World Server",
+		"Panes",
 		"1.0.0",
 		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(true, true),
+		server.WithPromptCapabilities(true),
+		server.WithLogging(),
+		server.WithCompletions(),
+		server.WithResourceCompletionProvider(&fileCompletions{store: store}),
+		server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(calls.toolMiddleware),
+		server.WithToolHandlerMiddleware(limits.toolMiddleware),
+		server.WithToolHandlerMiddleware(perms.toolMiddleware),
+		server.WithToolFilter(perms.toolFilter),
+		server.WithResourceHandlerMiddleware(limits.resourceMiddleware),
+		server.WithResourceHandlerMiddleware(perms.resourceMiddleware),
+		server.WithPaginationLimit(pageSize),
 	)
+	p := &Server{
+		MCP:           s,
+		store:         store,
+		files:         newFileResources(store),
+		prompts:       newPromptLibrary(s, store),
+		subscriptions: newSubscriptions(),
+		extensions:    newExtensions(s),
+		sessionOwners: newSessionOwners(),
+		sessions:      newSessions(),
+		permissions:   perms,
+		limits:        limits,
+		pageSize:      pageSize,
+	}
 
-	// Define a simple tool
-	tool := mcp.NewTool("hello_world",
-		mcp.WithDescription("Say hello to someone"),
-		mcp.WithString("name",
-			mcp.Required(),
-			mcp.Description("Name of the person to greet"),
-		),
-	)
+	// Tools come from the tool providers enabled in the config
+	if err := registerToolProviders(s, store, cfg.ToolProviders); err != nil {
+		return nil, err
+	}
+	// Files written by tools are published like uploads
+	hooks.AddAfterCallTool(func(ctx context.Context, id any, message *mcp.CallToolRequest, result any) {
+		if result, ok := result.(*mcp.CallToolResult); !ok || result.IsError {
+			return
+		}
+		switch message.Params.Name {
+		case toolCreateFile:
+			p.FilesChanged()
+		case toolUpdateFile, toolAppendFile:
+			p.FileUpdated(message.GetString("filename", ""))
+		}
+	})
+	// Tool calls can be cancelled by the client with notifications/cancelled
+	hooks.AddBeforeCallTool(calls.before)
+	hooks.AddAfterCallTool(calls.after)
+	hooks.AddOnError(calls.failed)
+	s.AddNotificationHandler(methodCancelled, calls.cancelled)
+	// Downstream servers are re-exported under their namespace
+	if err := p.registerGateway(gw, hooks); err != nil {
+		return nil, err
+	}
 
-	// Add tool handler
-	s.AddTool(tool, helloHandler)
+	// Publish uploaded files as resources, read through the template by filename.
+	// resources/list pages through the files table itself, by row ID, in front of the transports
+	s.AddResourceTemplate(fileTemplate(), p.files.read)
+	p.extensions.add(string(mcp.MethodResourcesList), p.handleListResources)
 
-	// Start the stdio server
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Server error: %v\n", err)
+	// Publish the stored prompt library, refreshing from the prompts table before every list or get
+	if err := p.prompts.Sync(); err != nil {
+		slog.Error("Failed to register prompts", "error", err)
 	}
+	hooks.AddBeforeListPrompts(func(ctx context.Context, id any, message *mcp.ListPromptsRequest) {
+		p.PromptsChanged()
+	})
+	hooks.AddBeforeGetPrompt(func(ctx context.Context, id any, message *mcp.GetPromptRequest) {
+		p.PromptsChanged()
+	})
+
+	// Resource subscriptions are not dispatched by mcp-go, Panes answers them in front of the transports
+	p.extensions.add(methodResourcesSubscribe, p.handleSubscribe)
+	p.extensions.add(methodResourcesUnsubscribe, p.handleUnsubscribe)
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		p.subscriptions.drop(session.SessionID())
+	})
+
+	// Every initialize, tool call, resource read and prompt get is written to the logs table
+	newAudit(store).register(hooks)
+
+	// HTTP sessions belong to the client that authenticated when opening them
+	hooks.AddOnRegisterSession(p.sessionOwners.register)
+	hooks.AddOnUnregisterSession(p.sessionOwners.unregister)
+
+	// Connected sessions are tracked from registration to unregistration, with the requests they make
+	hooks.AddOnRegisterSession(p.sessions.register)
+	hooks.AddOnUnregisterSession(p.sessions.unregister)
+	hooks.AddAfterInitialize(p.sessions.initialized)
+	p.extensions.observe = p.sessions.observe
+
+	return p, nil
 }
 
-func helloHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	name, err := request.RequireString("name")
-	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
-	}
+// ServeStdio serves the MCP server over stdin/stdout until stdin is closed or ctx is cancelled
+func (s *Server) ServeStdio(ctx context.Context) error {
+	stdout := &lockedWriter{w: os.Stdout}
+	stdin := s.extensions.stdio(ctx, os.Stdin, stdout)
+	return server.NewStdioServer(s.MCP).Listen(withTransport(ctx, config.TransportStdio), stdin, stdout)
+}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Hello, %s! 👋", name)), nil
+// StreamableHTTPHandler returns the Streamable HTTP transport, to be mounted at endpointPath on any http.ServeMux.
+// Sessions idle for idleTTL are ended. Every request needs the bearer API key of an active client
+func (s *Server) StreamableHTTPHandler(endpointPath string, idleTTL time.Duration) http.Handler {
+	// stateful so the IDs of ended or terminated sessions stop being accepted
+	ids := &server.InsecureStatefulSessionIdManager{}
+	streamable := server.NewStreamableHTTPServer(s.MCP,
+		server.WithEndpointPath(endpointPath),
+		server.WithSessionIdManager(ids),
+		server.WithSessionIdleTTL(idleTTL),
+	)
+	s.sessions.mu.Lock()
+	s.sessions.streamableIDs = ids
+	s.sessions.idleTTL = idleTTL
+	s.sessions.mu.Unlock()
+	return s.authenticated(trackedTransport(config.TransportStreamableHTTP, s.extensions.streamableHTTP(streamable)), streamableSessionID)
+}
+
+// SSEHandlers returns the legacy HTTP+SSE transport's stream and message handlers, its sessions end with their request context.
+// Every request needs the bearer API key of an active client
+func (s *Server) SSEHandlers(sseEndpoint, messageEndpoint string) (http.Handler, http.Handler) {
+	sse := server.NewSSEServer(s.MCP,
+		server.WithSSEEndpoint(sseEndpoint),
+		server.WithMessageEndpoint(messageEndpoint),
+		server.WithKeepAlive(true),
+	)
+	return s.authenticated(trackedSSE(sse.SSEHandler()), sseSessionID),
+		s.authenticated(s.extensions.sseMessage(sse, sse.MessageHandler()), sseSessionID)
 }
@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultPageSize is used when the configured page size is not positive
+const defaultPageSize = 100
+
+// listCursor is the position a resources/list page ended at: the ID of the last file row, or the URI of the last
+// downstream resource once every file was listed. Clients get it base64 encoded, the encoding mcp-go checks cursors
+// against, and must treat it as opaque
+type listCursor struct {
+	FileID int64  `json:"f,omitempty"`
+	URI    string `json:"u,omitempty"`
+}
+
+func (c listCursor) encode() mcp.Cursor {
+	data, _ := json.Marshal(c)
+	return mcp.Cursor(base64.StdEncoding.EncodeToString(data))
+}
+
+func decodeCursor(cursor mcp.Cursor) (listCursor, error) {
+	var c listCursor
+	if cursor == "" {
+		return c, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(cursor))
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+// listedResource is a resource on a resources/list page together with the cursor of the position right after it
+type listedResource struct {
+	resource mcp.Resource
+	position listCursor
+}
+
+// handleListResources answers resources/list with a page of what the client may read: files in row ID order first,
+// then the downstream resources in URI order. mcp-go would list registered resources only, files are not registered
+func (s *Server) handleListResources(ctx context.Context, sessionID string, message json.RawMessage) (any, error) {
+	var request mcp.ListResourcesRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return nil, err
+	}
+	if !s.sessions.connected(sessionID) {
+		return nil, fmt.Errorf("unknown session: %s", sessionID)
+	}
+	return s.resourcePage(ctx, request.Params.Cursor)
+}
+
+// resourcePage lists the readable resources after the cursor, up to a page
+func (s *Server) resourcePage(ctx context.Context, position mcp.Cursor) (*mcp.ListResourcesResult, error) {
+	cursor, err := decodeCursor(position)
+	if err != nil {
+		return nil, err
+	}
+
+	// one resource more than a page tells whether another page follows
+	var listed []listedResource
+	if cursor.URI == "" {
+		listed, err = s.listFileResources(ctx, cursor.FileID)
+		if err != nil {
+			return nil, &internalError{message: "could not list resources", err: err}
+		}
+	}
+	for _, resource := range s.gatewayResources {
+		if len(listed) > s.pageSize {
+			break
+		}
+		if resource.URI <= cursor.URI || !s.permissions.canReadGatewayResource(ctx, resource.URI) {
+			continue
+		}
+		listed = append(listed, listedResource{resource: resource, position: listCursor{URI: resource.URI}})
+	}
+
+	result := &mcp.ListResourcesResult{Resources: []mcp.Resource{}}
+	if len(listed) > s.pageSize {
+		listed = listed[:s.pageSize]
+		result.NextCursor = listed[len(listed)-1].position.encode()
+	}
+	for _, l := range listed {
+		result.Resources = append(result.Resources, l.resource)
+	}
+	return result, nil
+}
+
+// listFileResources lists the readable files after the given row ID, up to one more than a page
+func (s *Server) listFileResources(ctx context.Context, afterID int64) ([]listedResource, error) {
+	var listed []listedResource
+	for len(listed) <= s.pageSize {
+		files, err := s.store.ListFileMetadataPage(ctx, afterID, s.pageSize+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range s.permissions.readableFiles(ctx, files) {
+			listed = append(listed, listedResource{resource: fileResource(file), position: listCursor{FileID: file.ID}})
+		}
+		if len(files) <= s.pageSize {
+			break
+		}
+		afterID = files[len(files)-1].ID
+	}
+	return listed, nil
+}
@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/gateway"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Resource kinds and actions the permissions table grants on
+const (
+	resourceFile = "file"
+	resourceTool = "tool"
+	// resourceGateway grants on the resources and prompts of a downstream server, by server name
+	resourceGateway = "gateway"
+	actionRead      = "read"
+	actionWrite     = "write"
+	actionCall      = "call"
+)
+
+// permissions checks MCP requests against the permissions granted to the authenticated client.
+// Requests without an authenticated client come from the local stdio transport and are always allowed
+type permissions struct {
+	store *storage.SQLite
+}
+
+// allowed reports whether the client behind ctx may perform action on the resource, by a grant on resourceID or on every resource of that kind
+func (p *permissions) allowed(ctx context.Context, resource, action, resourceID string) bool {
+	client, ok := auth.ClientFromContext(ctx)
+	if !ok {
+		return true
+	}
+	allowed, err := p.store.CheckResourcePermission(ctx, client.ClientID, resource, action, resourceID)
+	if err != nil {
+		return false
+	}
+	if !allowed {
+		slog.Warn("MCP permission denied", "client", client.ClientID, "resource", resource, "action", action, "resource_id", resourceID)
+	}
+	return allowed
+}
+
+// refused tells the client behind ctx it was denied an action it asked for. Denied reads are not reported, they are
+// answered as if the resource did not exist
+func (p *permissions) refused(ctx context.Context, resource, action, resourceID string) {
+	message := fmt.Sprintf("permission denied: %s %s", action, resource)
+	if resourceID != "" {
+		message += " " + resourceID
+	}
+	notifyClient(ctx, mcp.LoggingLevelWarning, provider.EventPermissionDenied, message,
+		"resource", resource, "action", action, "resource_id", resourceID)
+}
+
+func (p *permissions) canReadFile(ctx context.Context, file types.FileMetadata) bool {
+	return p.allowed(ctx, resourceFile, actionRead, strconv.FormatInt(file.ID, 10))
+}
+
+// canWriteFile reports whether the client behind ctx may change a file, creating files takes a grant on every file
+func (p *permissions) canWriteFile(ctx context.Context, file types.FileMetadata) bool {
+	resourceID := ""
+	if file.ID != 0 {
+		resourceID = strconv.FormatInt(file.ID, 10)
+	}
+	return p.allowed(ctx, resourceFile, actionWrite, resourceID)
+}
+
+// readableFiles drops the files the client behind ctx may not read
+func (p *permissions) readableFiles(ctx context.Context, files []types.FileMetadata) []types.FileMetadata {
+	readable := []types.FileMetadata{}
+	for _, file := range files {
+		if p.canReadFile(ctx, file) {
+			readable = append(readable, file)
+		}
+	}
+	return readable
+}
+
+// toolMiddleware refuses calls to tools the client was not granted
+func (p *permissions) toolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !p.allowed(ctx, resourceTool, actionCall, request.Params.Name) {
+			p.refused(ctx, resourceTool, actionCall, request.Params.Name)
+			return mcp.NewToolResultErrorf("permission denied: tool %s", request.Params.Name), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// toolFilter hides the tools the client was not granted from tools/list
+func (p *permissions) toolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	allowed := []mcp.Tool{}
+	for _, tool := range tools {
+		if p.allowed(ctx, resourceTool, actionCall, tool.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
+
+// resourceMiddleware refuses reads of files and downstream resources the client was not granted, answering as if they did not exist
+func (p *permissions) resourceMiddleware(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if downstream, _, ok := gateway.ServerOfResource(request.Params.URI); ok {
+			if !p.allowed(ctx, resourceGateway, actionRead, downstream) {
+				return nil, fmt.Errorf("resource not found: %s", request.Params.URI)
+			}
+			return next(ctx, request)
+		}
+		filename, err := filenameFromURI(request.Params.URI)
+		if err != nil {
+			return next(ctx, request)
+		}
+		file, err := p.store.GetFileMetadataByName(ctx, filename)
+		if err == nil && !p.canReadFile(ctx, file) {
+			return nil, fmt.Errorf("file not found: %s", filename)
+		}
+		return next(ctx, request)
+	}
+}
+
+// canReadGatewayResource reports whether the client behind ctx was granted the downstream server a resource URI belongs to
+func (p *permissions) canReadGatewayResource(ctx context.Context, uri string) bool {
+	downstream, _, ok := gateway.ServerOfResource(uri)
+	return !ok || p.allowed(ctx, resourceGateway, actionRead, downstream)
+}
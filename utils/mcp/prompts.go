@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aaryansinhaa/panes/utils/gateway"
+	"github.com/aaryansinhaa/panes/utils/prompts"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// promptLibrary keeps the MCP prompt list in step with the prompts table
+type promptLibrary struct {
+	mcpServer  *server.MCPServer
+	store      *storage.SQLite
+	mu         sync.Mutex
+	registered map[string]types.Prompt
+}
+
+func newPromptLibrary(s *server.MCPServer, store *storage.SQLite) *promptLibrary {
+	return &promptLibrary{
+		mcpServer:  s,
+		store:      store,
+		registered: make(map[string]types.Prompt),
+	}
+}
+
+func storedPrompt(prompt types.Prompt) mcp.Prompt {
+	options := []mcp.PromptOption{mcp.WithPromptDescription(prompt.Description)}
+	for _, argument := range prompt.Arguments {
+		argumentOptions := []mcp.ArgumentOption{mcp.ArgumentDescription(argumentDescription(argument))}
+		if argument.Required {
+			argumentOptions = append(argumentOptions, mcp.RequiredArgument())
+		}
+		options = append(options, mcp.WithArgument(argument.Name, argumentOptions...))
+	}
+	return mcp.NewPrompt(prompt.Name, options...)
+}
+
+// argumentDescription mentions the argument type, which MCP prompt arguments have no field for
+func argumentDescription(argument types.PromptArgument) string {
+	if argument.Type == "" || argument.Type == prompts.ArgumentString {
+		return argument.Description
+	}
+	if argument.Description == "" {
+		return fmt.Sprintf("(%s)", argument.Type)
+	}
+	return fmt.Sprintf("%s (%s)", argument.Description, argument.Type)
+}
+
+// Sync registers a prompt for every new or changed row of the prompts table and drops the ones whose rows are gone
+func (p *promptLibrary) Sync() error {
+	stored, err := p.store.ListPrompts()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]types.Prompt, len(stored))
+	var added []server.ServerPrompt
+	for _, prompt := range stored {
+		// stored before such names were refused, it would shadow or pass for a downstream prompt
+		if strings.Contains(prompt.Name, gateway.Separator) {
+			slog.Warn("Prompt not published, its name is kept for downstream servers", "prompt", prompt.Name)
+			continue
+		}
+		current[prompt.Name] = prompt
+		if registered, ok := p.registered[prompt.Name]; ok && reflect.DeepEqual(registered, prompt) {
+			continue
+		}
+		added = append(added, server.ServerPrompt{Prompt: storedPrompt(prompt), Handler: p.get})
+	}
+	if len(added) > 0 {
+		p.mcpServer.AddPrompts(added...)
+	}
+	var removed []string
+	for name := range p.registered {
+		if _, ok := current[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	if len(removed) > 0 {
+		p.mcpServer.DeletePrompts(removed...)
+	}
+	p.registered = current
+	return nil
+}
+
+// get renders the latest stored version of a prompt with the arguments given by the client
+func (p *promptLibrary) get(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	prompt, err := p.store.GetPromptByName(request.Params.Name)
+	if err != nil {
+		return nil, fmt.Errorf("prompt not found: %s", request.Params.Name)
+	}
+	text, err := prompts.Render(prompt, request.Params.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewGetPromptResult(prompt.Description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	}), nil
+}
+
+// PromptsChanged refreshes the published prompts after the prompts table was modified,
+// which notifies every connected session that the prompt list changed
+func (s *Server) PromptsChanged() {
+	if err := s.prompts.Sync(); err != nil {
+		slog.Error("Failed to refresh prompts", "error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Logger names Panes in the log messages it sends to MCP clients
+const Logger = "panes"
+
+// Events Panes tells MCP clients about, in the data of its log messages
+const (
+	EventPermissionDenied = "permission_denied"
+	EventTruncated        = "truncated"
+	EventRateLimited      = "rate_limited"
+)
+
+// Notify sends a log message about a tool call to the calling client, if it asked for messages of that level with
+// logging/setLevel. The args are key-value pairs added to the message data, as with slog
+func Notify(ctx context.Context, level mcp.LoggingLevel, event, message string, args ...any) {
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+	if err := srv.SendLogMessageToClient(ctx, LogMessage(level, event, message, args...)); err != nil {
+		slog.Debug("Could not send log message to MCP client", "event", event, "error", err)
+	}
+}
+
+// LogMessage builds the log message notification Notify sends, its data holds the event, the message and the args
+func LogMessage(level mcp.LoggingLevel, event, message string, args ...any) mcp.LoggingMessageNotification {
+	data := map[string]any{"event": event, "message": message}
+	for i := 0; i+1 < len(args); i += 2 {
+		if key, ok := args[i].(string); ok {
+			data[key] = args[i+1]
+		}
+	}
+	return mcp.NewLoggingMessageNotification(level, Logger, data)
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// methodProgress is the notification reporting the progress of a request
+const methodProgress = "notifications/progress"
+
+// progressTokenKey carries the progress token the client sent with a tool call
+type progressTokenKey struct{}
+
+// WithProgressToken returns a context reporting the progress of a tool call under the token the client sent with it,
+// Panes sets it before calling the handler
+func WithProgressToken(ctx context.Context, token mcp.ProgressToken) context.Context {
+	if token == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressTokenKey{}, token)
+}
+
+// Progress tells the calling client how far a tool call got, if it sent a progress token with the call. total is 0
+// when it is not known, progress must increase from one call to the next
+func Progress(ctx context.Context, progress, total float64, message string) {
+	token, ok := ctx.Value(progressTokenKey{}).(mcp.ProgressToken)
+	if !ok {
+		return
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return
+	}
+	params := map[string]any{"progressToken": token, "progress": progress}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	if err := srv.SendNotificationToClient(ctx, methodProgress, params); err != nil {
+		slog.Debug("Could not send progress to MCP client", "error", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package provider is the registry of MCP tool providers compiled into Panes.
+//
+// A tool pack lives in its own Go package, registers its provider from an init function
+// and is compiled in with a blank import in cmd/panes:
+//
+//	func init() {
+//		provider.Register(myPack{})
+//	}
+//
+// The provider is then enabled by listing its name under mcp_server.tool_providers in the config.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ToolProvider declares a set of MCP tools together with their handlers
+type ToolProvider interface {
+	// Name is the name the provider is enabled by in the config, it must be unique
+	Name() string
+	// Tools returns the tools of the provider, backed by the shared storage
+	Tools(store *storage.SQLite) ([]Tool, error)
+}
+
+// Tool is a tool definition and the handler answering its calls
+type Tool struct {
+	Definition mcp.Tool
+	Handler    ToolHandler
+}
+
+// ToolHandler answers a tool call made by the given caller
+type ToolHandler func(ctx context.Context, caller Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+// Caller identifies who is calling a tool. ClientID is empty for the local stdio transport,
+// whose ClientName is the name the client gave on initialize
+type Caller struct {
+	ClientID   string
+	ClientName string
+}
+
+// Local reports whether the call came from the local stdio transport rather than an authenticated client
+func (c Caller) Local() bool {
+	return c.ClientID == ""
+}
+
+var (
+	mu        sync.RWMutex
+	providers = make(map[string]ToolProvider)
+)
+
+// Register makes a tool provider available to be enabled in the config. It panics if a provider with the same name is already registered
+func Register(p ToolProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+	if p == nil {
+		panic("provider: Register provider is nil")
+	}
+	if _, dup := providers[p.Name()]; dup {
+		panic("provider: Register called twice for provider " + p.Name())
+	}
+	providers[p.Name()] = p
+}
+
+// Lookup returns the registered provider with the given name
+func Lookup(name string) (ToolProvider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool provider %q, registered providers are %v", name, namesLocked())
+	}
+	return p, nil
+}
+
+// Names lists the registered providers, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
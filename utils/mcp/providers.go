@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/gateway"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerToolProviders adds the tools of every enabled provider to the MCP server, refusing tool names declared twice
+// and names containing the separator of downstream tool names
+func registerToolProviders(s *server.MCPServer, store *storage.SQLite, enabled []string) error {
+	declaredBy := make(map[string]string)
+	for _, name := range enabled {
+		p, err := provider.Lookup(name)
+		if err != nil {
+			return err
+		}
+		tools, err := p.Tools(store)
+		if err != nil {
+			return fmt.Errorf("tool provider %s: %w", name, err)
+		}
+
+		serverTools := make([]server.ServerTool, 0, len(tools))
+		for _, tool := range tools {
+			if strings.Contains(tool.Definition.Name, gateway.Separator) {
+				return fmt.Errorf("tool %s of the %s tool provider: names containing %q are kept for downstream servers", tool.Definition.Name, name, gateway.Separator)
+			}
+			if other, dup := declaredBy[tool.Definition.Name]; dup {
+				return fmt.Errorf("tool %s is declared by both the %s and %s tool providers", tool.Definition.Name, other, name)
+			}
+			declaredBy[tool.Definition.Name] = name
+			serverTools = append(serverTools, server.ServerTool{Tool: tool.Definition, Handler: withCaller(tool.Handler)})
+		}
+		s.AddTools(serverTools...)
+		slog.Info("Registered tool provider", "provider", name, "tools", len(tools))
+	}
+	return nil
+}
+
+// withCaller adapts a provider tool handler to mcp-go, resolving the caller from the request context
+func withCaller(handler provider.ToolHandler) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return handler(ctx, callerFromContext(ctx), request)
+	}
+}
+
+// callerFromContext identifies the authenticated client of a request, or the local stdio client by the name it gave on initialize
+func callerFromContext(ctx context.Context) provider.Caller {
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		return provider.Caller{ClientID: client.ClientID, ClientName: client.ClientName}
+	}
+	return provider.Caller{ClientName: clientName(ctx, nil)}
+}
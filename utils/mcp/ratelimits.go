@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sweepInterval is how often buckets that refilled completely are dropped, a full bucket is the same as none
+const sweepInterval = time.Minute
+
+// Limit kinds reported to throttled clients
+const (
+	limitRate  = "rate_limit"
+	limitQuota = "daily_quota"
+)
+
+// throttled is the error a call over a rate limit or daily quota is refused with
+type throttled struct {
+	limit      string
+	tool       string // set when the tool has its own limits
+	retryAfter int64  // seconds
+	reason     string
+}
+
+func (t *throttled) Error() string {
+	return fmt.Sprintf("%s, retry after %d seconds", t.reason, t.retryAfter)
+}
+
+// bucket is a token bucket refilled continuously at rate tokens per second up to its capacity
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will be full again if nothing is taken from it
+}
+
+// rateLimits enforces the rate limits and daily quotas of the authenticated clients on tool calls and resource reads.
+// Requests without an authenticated client come from the local stdio transport and are never limited
+type rateLimits struct {
+	store     *storage.SQLite
+	mu        sync.Mutex
+	buckets   map[string]*bucket // client ID and scope -> bucket
+	lastSweep time.Time
+}
+
+func newRateLimits(store *storage.SQLite) *rateLimits {
+	return &rateLimits{store: store, buckets: make(map[string]*bucket)}
+}
+
+// usageDay is the UTC day daily quotas are counted on
+func usageDay(now time.Time) string {
+	return now.UTC().Format(time.DateOnly)
+}
+
+// burst is how many calls a bucket holds, the per-minute rate unless set
+func burst(limits types.ClientLimits) float64 {
+	if limits.Burst > 0 {
+		return float64(limits.Burst)
+	}
+	return math.Max(1, math.Ceil(limits.RateLimit))
+}
+
+func bucketKey(clientID, scope string) string {
+	return clientID + "\x00" + scope
+}
+
+// take removes a token from the bucket of the client and scope, or returns how long until one is available
+func (l *rateLimits) take(clientID, scope string, limits types.ClientLimits, now time.Time) (bool, time.Duration) {
+	if limits.RateLimit <= 0 {
+		return true, 0
+	}
+	capacity := burst(limits)
+	perSecond := limits.RateLimit / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	key := bucketKey(clientID, scope)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((capacity - b.tokens) / perSecond * float64(time.Second)))
+	if allowed {
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+}
+
+// sweep drops the buckets that refilled completely, at most once per sweepInterval
+func (l *rateLimits) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// reset drops the buckets of a client, whose limits changed
+func (l *rateLimits) reset(clientID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prefix := bucketKey(clientID, "")
+	for key := range l.buckets {
+		if strings.HasPrefix(key, prefix) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// check counts a call of the client behind ctx, returning the error to refuse it with when it is over a limit
+func (l *rateLimits) check(ctx context.Context, method mcp.MCPMethod, target, tool string) *throttled {
+	client, ok := auth.ClientFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	limits, scope := client.ClientLimits, ""
+	if override, ok := client.ToolLimits[tool]; ok && tool != "" {
+		limits, scope = override, tool
+	}
+
+	now := time.Now()
+	if ok, wait := l.take(client.ClientID, scope, limits, now); !ok {
+		return l.refuse(ctx, client, method, target, &throttled{
+			limit:      limitRate,
+			tool:       scope,
+			retryAfter: int64(math.Ceil(wait.Seconds())),
+			reason:     fmt.Sprintf("rate limit of %g calls per minute exceeded", limits.RateLimit),
+		})
+	}
+	_, err := l.store.CountClientCall(client.ClientID, usageDay(now), scope, limits.DailyQuota)
+	if errors.Is(err, sql.ErrNoRows) {
+		tomorrow := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+		return l.refuse(ctx, client, method, target, &throttled{
+			limit:      limitQuota,
+			tool:       scope,
+			retryAfter: int64(math.Ceil(tomorrow.Sub(now).Seconds())),
+			reason:     fmt.Sprintf("daily quota of %d calls exceeded", limits.DailyQuota),
+		})
+	}
+	// a failure to count is logged by the store and does not hold the call back
+	return nil
+}
+
+// refuse tells the client about a throttled call, which the audit hooks record as a warning
+func (l *rateLimits) refuse(ctx context.Context, client types.Client, method mcp.MCPMethod, target string, refusal *throttled) *throttled {
+	if refusal.tool != "" {
+		refusal.reason += " for tool " + refusal.tool
+	}
+	slog.Warn("MCP call throttled", "client", client.ClientID, "method", method, "target", target, "limit", refusal.limit)
+	notifyClient(ctx, mcp.LoggingLevelWarning, provider.EventRateLimited, refusal.reason,
+		"method", method, "target", target, "limit", refusal.limit, "retry_after_seconds", refusal.retryAfter)
+	return refusal
+}
+
+// toolMiddleware throttles tools/call, by the limits of the tool when the client has some for it.
+// A refused call is answered with a JSON-RPC error, the tool did not run
+func (l *rateLimits) toolMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if refusal := l.check(ctx, mcp.MethodToolsCall, request.Params.Name, request.Params.Name); refusal != nil {
+			return nil, refusal
+		}
+		return next(ctx, request)
+	}
+}
+
+// resourceMiddleware throttles resources/read
+func (l *rateLimits) resourceMiddleware(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if refusal := l.check(ctx, mcp.MethodResourcesRead, request.Params.URI, ""); refusal != nil {
+			return nil, refusal
+		}
+		return next(ctx, request)
+	}
+}
+
+// ResetRateLimits forgets the calls a client made against its rate limits, after its limits were changed
+func (s *Server) ResetRateLimits(clientID string) {
+	s.limits.reset(clientID)
+}
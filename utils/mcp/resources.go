@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/extract"
+	"github.com/aaryansinhaa/panes/utils/fileio"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// fileResourcePrefix is the URI prefix under which uploaded files are published
+	fileResourcePrefix = "panes://files/"
+	// fileResourceTemplate lets clients address any uploaded file by name
+	fileResourceTemplate = fileResourcePrefix + "{filename}"
+)
+
+// fileResources serves the uploaded files through the panes://files/ resource template, one row looked up per read.
+// They are not registered with mcp-go one by one, resources/list pages through the files table instead
+type fileResources struct {
+	store *storage.SQLite
+}
+
+func newFileResources(store *storage.SQLite) *fileResources {
+	return &fileResources{store: store}
+}
+
+// fileResourceURI builds the resource URI of an uploaded file
+func fileResourceURI(filename string) string {
+	return fileResourcePrefix + url.PathEscape(filename)
+}
+
+// filenameFromURI extracts the filename from a file resource URI
+func filenameFromURI(uri string) (string, error) {
+	if !strings.HasPrefix(uri, fileResourcePrefix) {
+		return "", fmt.Errorf("not a file resource: %s", uri)
+	}
+	return url.PathUnescape(strings.TrimPrefix(uri, fileResourcePrefix))
+}
+
+func fileTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(
+		fileResourceTemplate,
+		"Uploaded file",
+		mcp.WithTemplateDescription("Any file uploaded to Panes, addressed by its filename"),
+	)
+}
+
+func fileResource(file types.FileMetadata) mcp.Resource {
+	return mcp.NewResource(
+		fileResourceURI(file.Filename),
+		file.Filename,
+		mcp.WithResourceDescription(fmt.Sprintf("%s (%d bytes, uploaded %s by %s)", file.OriginalName, file.FileSize, file.UploadedAt, file.Owner)),
+		mcp.WithMIMEType(file.MimeType),
+	)
+}
+
+// read serves the text extracted from an uploaded document, or the contents of any other file as text or as a
+// base64 blob depending on its MIME type
+func (f *fileResources) read(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	filename, err := filenameFromURI(request.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+	file, err := f.store.GetFileMetadataByName(ctx, filename)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", filename)
+	}
+	if rendition, err := f.store.GetRendition(ctx, file.ID); err == nil {
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{URI: request.Params.URI, MIMEType: rendition.MimeType, Text: rendition.Content},
+		}, nil
+	}
+	data, err := fileio.ReadAll(ctx, file.FilePath)
+	if err != nil {
+		slog.Error("Failed to read file for resource", "filename", filename, "error", err)
+		return nil, fmt.Errorf("could not read file: %s", filename)
+	}
+
+	mimeType := file.MimeType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if extract.IsText(mimeType) {
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{URI: request.Params.URI, MIMEType: mimeType, Text: string(data)},
+		}, nil
+	}
+	return []mcp.ResourceContents{
+		mcp.BlobResourceContents{URI: request.Params.URI, MIMEType: mimeType, Blob: base64.StdEncoding.EncodeToString(data)},
+	}, nil
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/aaryansinhaa/panes/utils/embeddings"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// semanticMatches is the structured result of semantic_search
+type semanticMatches struct {
+	Results []semanticMatch `json:"results"`
+}
+
+// semanticMatch is a file found by semantic_search
+type semanticMatch struct {
+	File  fileInfo `json:"file"`
+	Chunk string   `json:"chunk"` // the part of the file text closest to the query
+	Score float64  `json:"score"` // cosine similarity, higher is better
+}
+
+// semanticSearchProvider serves the semantic_search tool over the embedding index, it has no tools when semantic
+// search is not configured
+type semanticSearchProvider struct {
+	index *embeddings.Index
+}
+
+// NewSemanticSearchProvider creates the "semantic" tool provider, index is nil without semantic search
+func NewSemanticSearchProvider(index *embeddings.Index) provider.ToolProvider {
+	return &semanticSearchProvider{index: index}
+}
+
+func (p *semanticSearchProvider) Name() string {
+	return "semantic"
+}
+
+func (p *semanticSearchProvider) Tools(store *storage.SQLite) ([]provider.Tool, error) {
+	if p.index == nil {
+		return nil, nil
+	}
+	return []provider.Tool{
+		{Definition: mcp.NewTool("semantic_search",
+			mcp.WithDescription("Search the text of the uploaded files by meaning rather than by keywords, closest files first with the passage that matched"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithOutputSchema[semanticMatches](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Question or description of what to find"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of files to return"),
+				mcp.DefaultNumber(defaultSearchLimit),
+				mcp.Min(1),
+				mcp.Max(maxListLimit),
+			),
+		), Handler: func(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			query, err := request.RequireString("query")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			limit := clampLimit(request.GetInt("limit", defaultSearchLimit), defaultSearchLimit)
+
+			provider.Progress(ctx, 0, 1, "searching the embedded file text")
+			matches, err := p.index.Search(ctx, caller.ClientID, query, limit)
+			if err != nil {
+				return mcp.NewToolResultErrorFromErr("could not search file contents", err), nil
+			}
+			provider.Progress(ctx, 1, 1, "")
+			result := semanticMatches{Results: []semanticMatch{}}
+			for _, match := range matches {
+				result.Results = append(result.Results, semanticMatch{File: newFileInfo(match.File), Chunk: match.Chunk, Score: match.Score})
+			}
+			return structuredResult(result)
+		}},
+	}, nil
+}
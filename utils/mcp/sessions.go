@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+var (
+	// ErrSessionNotFound is returned when terminating a session that is not connected
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionNotTerminable is returned when terminating the stdio session, which ends only with the process
+	ErrSessionNotTerminable = errors.New("stdio sessions cannot be terminated")
+)
+
+// transportKey carries the transport a request came in on
+type transportKey struct{}
+
+// cancelKey carries the function that ends the request an SSE session lives in
+type cancelKey struct{}
+
+func withTransport(ctx context.Context, transport string) context.Context {
+	return context.WithValue(ctx, transportKey{}, transport)
+}
+
+// session is what the registry knows about a connected session
+type session struct {
+	info   types.MCPSession
+	start  time.Time
+	last   time.Time
+	cancel context.CancelFunc // ends an SSE session
+}
+
+// sessions tracks the connected MCP sessions, from registration to unregistration
+type sessions struct {
+	mu        sync.Mutex
+	bySession map[string]*session
+	// streamableIDs terminates Streamable HTTP sessions, nil until that transport is served
+	streamableIDs server.SessionIdManager
+	// idleTTL is how long a Streamable HTTP session may go without a request, mcp-go ends it after that
+	idleTTL time.Duration
+}
+
+func newSessions() *sessions {
+	return &sessions{bySession: make(map[string]*session)}
+}
+
+// get returns the entry of a session, creating it: Streamable HTTP sessions are registered after their initialize is answered
+func (s *sessions) get(sessionID string, now time.Time) *session {
+	entry, ok := s.bySession[sessionID]
+	if !ok {
+		entry = &session{info: types.MCPSession{SessionID: sessionID, Calls: map[string]int64{}}, start: now, last: now}
+		s.bySession[sessionID] = entry
+	}
+	return entry
+}
+
+// prune drops the Streamable HTTP sessions idle for longer than idleTTL, including those that never got registered
+// after their initialize or whose unregistration was missed. SSE sessions last as long as their stream
+func (s *sessions) prune(now time.Time) {
+	if s.idleTTL <= 0 {
+		return
+	}
+	for sessionID, entry := range s.bySession {
+		if entry.info.Transport != config.TransportSSE && sessionID != stdioSessionID && now.Sub(entry.last) > s.idleTTL {
+			delete(s.bySession, sessionID)
+		}
+	}
+}
+
+func (s *sessions) register(ctx context.Context, clientSession server.ClientSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	entry := s.get(clientSession.SessionID(), time.Now())
+	if transport, ok := ctx.Value(transportKey{}).(string); ok {
+		entry.info.Transport = transport
+	}
+	if cancel, ok := ctx.Value(cancelKey{}).(context.CancelFunc); ok {
+		entry.cancel = cancel
+	}
+	if client, ok := auth.ClientFromContext(ctx); ok {
+		entry.info.ClientID = client.ClientID
+		entry.info.ClientName = client.ClientName
+	}
+	slog.Info("MCP session started", "session", clientSession.SessionID(), "transport", entry.info.Transport, "client", entry.info.ClientID)
+}
+
+func (s *sessions) unregister(ctx context.Context, clientSession server.ClientSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bySession, clientSession.SessionID())
+	slog.Info("MCP session ended", "session", clientSession.SessionID())
+}
+
+// initialized records the client software and protocol version a session agreed on
+func (s *sessions) initialized(ctx context.Context, id any, request *mcp.InitializeRequest, result *mcp.InitializeResult) {
+	clientSession := server.ClientSessionFromContext(ctx)
+	if clientSession == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.get(clientSession.SessionID(), time.Now())
+	entry.info.ClientInfo = strings.TrimSpace(request.Params.ClientInfo.Name + " " + request.Params.ClientInfo.Version)
+	entry.info.ProtocolVersion = result.ProtocolVersion
+	if entry.info.ClientName == "" {
+		entry.info.ClientName = request.Params.ClientInfo.Name
+	}
+}
+
+// connected reports whether a session is connected, Streamable HTTP sessions once their initialize was answered
+func (s *sessions) connected(sessionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.bySession[sessionID]
+	return ok
+}
+
+// observe counts a request of a connected session
+func (s *sessions) observe(sessionID, method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.bySession[sessionID]
+	if !ok {
+		return
+	}
+	entry.last = time.Now()
+	entry.info.Requests++
+	entry.info.Calls[method]++
+}
+
+// Sessions lists the connected MCP sessions, oldest first
+func (s *Server) Sessions() []types.MCPSession {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+	s.sessions.prune(time.Now())
+	list := make([]types.MCPSession, 0, len(s.sessions.bySession))
+	for _, entry := range s.sessions.bySession {
+		info := entry.info
+		info.StartedAt = entry.start.UTC().Format(time.RFC3339)
+		info.LastActivityAt = entry.last.UTC().Format(time.RFC3339)
+		info.Calls = make(map[string]int64, len(entry.info.Calls))
+		for method, calls := range entry.info.Calls {
+			info.Calls[method] = calls
+		}
+		list = append(list, info)
+	}
+	slices.SortFunc(list, func(a, b types.MCPSession) int {
+		return strings.Compare(a.StartedAt+a.SessionID, b.StartedAt+b.SessionID)
+	})
+	return list
+}
+
+// TerminateSession ends an HTTP session: a Streamable HTTP session ID stops being accepted and an SSE stream is closed
+func (s *Server) TerminateSession(sessionID string) error {
+	s.sessions.mu.Lock()
+	entry, ok := s.sessions.bySession[sessionID]
+	var transport string
+	var cancel context.CancelFunc
+	if ok {
+		transport, cancel = entry.info.Transport, entry.cancel
+	}
+	ids := s.sessions.streamableIDs
+	s.sessions.mu.Unlock()
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	switch transport {
+	case config.TransportSSE:
+		cancel()
+	case config.TransportStreamableHTTP:
+		// like a DELETE from the client itself: the session ID stops being accepted and the session is unregistered.
+		// What the transport keeps for the session is dropped by its idle sweeper
+		if _, err := ids.Terminate(sessionID); err != nil {
+			return fmt.Errorf("could not terminate session: %w", err)
+		}
+		s.MCP.UnregisterSession(context.Background(), sessionID)
+	default:
+		return ErrSessionNotTerminable
+	}
+	slog.Info("MCP session terminated", "session", sessionID, "transport", transport)
+	return nil
+}
+
+// trackedSSE binds every SSE stream to a context the registry can cancel to terminate its session
+func trackedSSE(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(withTransport(r.Context(), config.TransportSSE))
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, cancelKey{}, cancel)))
+	})
+}
+
+// trackedTransport marks the requests of a transport so the registry knows what its sessions came in on
+func trackedTransport(transport string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withTransport(r.Context(), transport)))
+	})
+}
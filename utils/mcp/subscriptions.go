@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// subscriptions tracks which sessions asked to be told when which resources are updated
+type subscriptions struct {
+	mu        sync.Mutex
+	bySession map[string]map[string]struct{}
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{bySession: make(map[string]map[string]struct{})}
+}
+
+func (s *subscriptions) subscribe(sessionID, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bySession[sessionID] == nil {
+		s.bySession[sessionID] = make(map[string]struct{})
+	}
+	s.bySession[sessionID][uri] = struct{}{}
+}
+
+func (s *subscriptions) unsubscribe(sessionID, uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bySession[sessionID], uri)
+	if len(s.bySession[sessionID]) == 0 {
+		delete(s.bySession, sessionID)
+	}
+}
+
+// drop forgets every subscription of a session that went away
+func (s *subscriptions) drop(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bySession, sessionID)
+}
+
+// subscribers lists the sessions subscribed to the given resource URI
+func (s *subscriptions) subscribers(uri string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sessions []string
+	for sessionID, uris := range s.bySession {
+		if _, ok := uris[uri]; ok {
+			sessions = append(sessions, sessionID)
+		}
+	}
+	return sessions
+}
+
+func (s *Server) handleSubscribe(ctx context.Context, sessionID string, message json.RawMessage) (any, error) {
+	var request mcp.SubscribeRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return nil, err
+	}
+	filename, err := filenameFromURI(request.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+	// subscriptions are dropped when their session ends, a session that never started would keep them forever
+	if !s.sessions.connected(sessionID) {
+		return nil, fmt.Errorf("unknown session: %s", sessionID)
+	}
+	file, err := s.store.GetFileMetadataByName(ctx, filename)
+	if err != nil || !s.permissions.canReadFile(ctx, file) {
+		return nil, fmt.Errorf("resource not found: %s", request.Params.URI)
+	}
+	s.subscriptions.subscribe(sessionID, request.Params.URI)
+	slog.Info("MCP session subscribed to resource", "session", sessionID, "uri", request.Params.URI)
+	return mcp.EmptyResult{}, nil
+}
+
+func (s *Server) handleUnsubscribe(ctx context.Context, sessionID string, message json.RawMessage) (any, error) {
+	var request mcp.UnsubscribeRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return nil, err
+	}
+	if request.Params.URI == "" {
+		return nil, fmt.Errorf("uri is required")
+	}
+	s.subscriptions.unsubscribe(sessionID, request.Params.URI)
+	return mcp.EmptyResult{}, nil
+}
+
+// FilesChanged tells every connected session that the resource list changed, after rows were added to or removed
+// from the files table
+func (s *Server) FilesChanged() {
+	s.MCP.SendNotificationToAllClients(mcp.MethodNotificationResourcesListChanged, nil)
+}
+
+// FileUpdated tells the sessions subscribed to a file that its content was replaced
+func (s *Server) FileUpdated(filename string) {
+	uri := fileResourceURI(filename)
+	for _, sessionID := range s.subscriptions.subscribers(uri) {
+		err := s.MCP.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+		if errors.Is(err, server.ErrSessionNotFound) {
+			s.subscriptions.drop(sessionID)
+			continue
+		}
+		if err != nil {
+			slog.Error("Failed to notify resource update", "session", sessionID, "uri", uri, "error", err)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/aaryansinhaa/panes/utils/embeddings"
+	"github.com/aaryansinhaa/panes/utils/extract"
+	"github.com/aaryansinhaa/panes/utils/fileio"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultListLimit   = 50
+	maxListLimit       = 200
+	defaultSearchLimit = 20
+	defaultReadLength  = 64 << 10 // 64 KB
+	maxReadLength      = 1 << 20  // 1 MB
+)
+
+// fileInfo is a file as MCP clients see it, without the path it is stored at on the server
+type fileInfo struct {
+	ID           int64  `json:"id"`
+	OriginalName string `json:"original_name"`
+	Filename     string `json:"filename"`
+	FileSize     int64  `json:"file_size"`
+	MimeType     string `json:"mime_type"`
+	UploadedAt   string `json:"uploaded_at"`
+	Owner        string `json:"owner"`
+}
+
+func newFileInfo(file types.FileMetadata) fileInfo {
+	return fileInfo{
+		ID:           file.ID,
+		OriginalName: file.OriginalName,
+		Filename:     file.Filename,
+		FileSize:     file.FileSize,
+		MimeType:     file.MimeType,
+		UploadedAt:   file.UploadedAt,
+		Owner:        file.Owner,
+	}
+}
+
+// newFileInfos converts a list of files, never returning nil so an empty list is encoded as []
+func newFileInfos(files []types.FileMetadata) []fileInfo {
+	infos := make([]fileInfo, 0, len(files))
+	for _, file := range files {
+		infos = append(infos, newFileInfo(file))
+	}
+	return infos
+}
+
+// fileList is the structured result of list_files
+type fileList struct {
+	Files      []fileInfo `json:"files"`
+	Total      int        `json:"total"`                 // number of files the client may read
+	Offset     int        `json:"offset"`                // files skipped before this page
+	NextOffset *int       `json:"next_offset,omitempty"` // offset of the next page, unset on the last one
+}
+
+// fileMatches is the structured result of search_files
+type fileMatches struct {
+	Files []fileInfo `json:"files"`
+}
+
+// contentMatch is a file found by search_content
+type contentMatch struct {
+	File    fileInfo `json:"file"`
+	Snippet string   `json:"snippet"` // matching text, matched terms wrapped in **
+	Score   float64  `json:"score"`   // BM25 relevance, higher is better
+}
+
+// contentMatches is the structured result of search_content
+type contentMatches struct {
+	Results []contentMatch `json:"results"`
+}
+
+// fileTools exposes the storage layer through MCP tools
+type fileTools struct {
+	store       *storage.SQLite
+	index       *embeddings.Index
+	permissions *permissions
+}
+
+// fileToolProvider is the built-in provider of the tools reading and writing the uploaded files
+type fileToolProvider struct {
+	index *embeddings.Index
+}
+
+// NewFileToolProvider creates the "files" tool provider, files written by its tools are indexed like uploads in the
+// embedding index, which is nil without semantic search
+func NewFileToolProvider(index *embeddings.Index) provider.ToolProvider {
+	return &fileToolProvider{index: index}
+}
+
+func (p *fileToolProvider) Name() string {
+	return "files"
+}
+
+func (p *fileToolProvider) Tools(store *storage.SQLite) ([]provider.Tool, error) {
+	t := &fileTools{store: store, index: p.index, permissions: &permissions{store: store}}
+	tools := []provider.Tool{
+		{Definition: mcp.NewTool("list_files",
+			mcp.WithDescription("List the files uploaded to Panes, one page at a time"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithOutputSchema[fileList](),
+			mcp.WithNumber("offset",
+				mcp.Description("Number of files to skip"),
+				mcp.DefaultNumber(0),
+				mcp.Min(0),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of files to return"),
+				mcp.DefaultNumber(defaultListLimit),
+				mcp.Min(1),
+				mcp.Max(maxListLimit),
+			),
+		), Handler: t.listFiles},
+		{Definition: mcp.NewTool("search_files",
+			mcp.WithDescription("Search the uploaded files by filename"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithOutputSchema[fileMatches](),
+			mcp.WithString("pattern",
+				mcp.Required(),
+				mcp.Description("Part of the filename to look for"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of files to return"),
+				mcp.DefaultNumber(defaultSearchLimit),
+				mcp.Min(1),
+				mcp.Max(maxListLimit),
+			),
+		), Handler: t.searchFiles},
+		{Definition: mcp.NewTool("search_content",
+			mcp.WithDescription("Search the text of the uploaded files, documents included, best matches first with a snippet in which the matched words are wrapped in **"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithOutputSchema[contentMatches](),
+			mcp.WithString("query",
+				mcp.Required(),
+				mcp.Description("Words the files must contain, end a word with * to match it as a prefix"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of files to return"),
+				mcp.DefaultNumber(defaultSearchLimit),
+				mcp.Min(1),
+				mcp.Max(maxListLimit),
+			),
+		), Handler: t.searchContent},
+		{Definition: mcp.NewTool("read_file",
+			mcp.WithDescription("Read the content of an uploaded file, large files can be read in several calls using offset and length"),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithString("filename",
+				mcp.Required(),
+				mcp.Description("Exact filename as returned by list_files or search_files"),
+			),
+			mcp.WithNumber("offset",
+				mcp.Description("Byte offset to start reading from"),
+				mcp.DefaultNumber(0),
+				mcp.Min(0),
+			),
+			mcp.WithNumber("length",
+				mcp.Description("Maximum number of bytes to read"),
+				mcp.DefaultNumber(defaultReadLength),
+				mcp.Min(1),
+				mcp.Max(maxReadLength),
+			),
+		), Handler: t.readFile},
+		{Definition: mcp.NewTool(toolCreateFile,
+			mcp.WithDescription("Create a new text file in Panes, such as a report or notes"),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOutputSchema[fileInfo](),
+			mcp.WithString("filename",
+				mcp.Required(),
+				mcp.Description("Name of the new file, without directories or spaces, its extension tells its type"),
+			),
+			mcp.WithString("content",
+				mcp.Required(),
+				mcp.Description("Text content of the file"),
+			),
+		), Handler: t.createFile},
+		{Definition: mcp.NewTool(toolUpdateFile,
+			mcp.WithDescription("Replace the content of an existing text file"),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithOutputSchema[fileInfo](),
+			mcp.WithString("filename",
+				mcp.Required(),
+				mcp.Description("Exact filename as returned by list_files or search_files"),
+			),
+			mcp.WithString("content",
+				mcp.Required(),
+				mcp.Description("New text content of the file"),
+			),
+		), Handler: t.updateFile},
+		{Definition: mcp.NewTool(toolAppendFile,
+			mcp.WithDescription("Add text to the end of an existing text file"),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOutputSchema[fileInfo](),
+			mcp.WithString("filename",
+				mcp.Required(),
+				mcp.Description("Exact filename as returned by list_files or search_files"),
+			),
+			mcp.WithString("content",
+				mcp.Required(),
+				mcp.Description("Text to add, include a leading newline to start a new line"),
+			),
+		), Handler: t.appendFile},
+	}
+	// without FTS5 there is no index for search_content to search
+	if !store.ContentSearch() {
+		tools = slices.DeleteFunc(tools, func(tool provider.Tool) bool { return tool.Definition.Name == "search_content" })
+	}
+	return tools, nil
+}
+
+func (t *fileTools) listFiles(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	offset := max(request.GetInt("offset", 0), 0)
+	limit := clampLimit(request.GetInt("limit", defaultListLimit), defaultListLimit)
+
+	files, total, err := t.store.ListReadableFiles(ctx, caller.ClientID, offset, limit)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("could not list files", err), nil
+	}
+
+	result := fileList{Files: newFileInfos(files), Total: total, Offset: offset}
+	if next := offset + len(files); next < total {
+		result.NextOffset = &next
+	}
+	return structuredResult(result)
+}
+
+func (t *fileTools) searchFiles(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pattern, err := request.RequireString("pattern")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := clampLimit(request.GetInt("limit", defaultSearchLimit), defaultSearchLimit)
+
+	files, err := t.store.SearchFilesByName(ctx, caller.ClientID, pattern, limit)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("could not search files", err), nil
+	}
+	return structuredResult(fileMatches{Files: newFileInfos(files)})
+}
+
+func (t *fileTools) searchContent(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := clampLimit(request.GetInt("limit", defaultSearchLimit), defaultSearchLimit)
+
+	provider.Progress(ctx, 0, 1, "searching file contents")
+	matches, err := t.store.SearchFileContent(ctx, caller.ClientID, query, limit)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("could not search file contents", err), nil
+	}
+	provider.Progress(ctx, 1, 1, "")
+	result := contentMatches{Results: []contentMatch{}}
+	for _, match := range matches {
+		result.Results = append(result.Results, contentMatch{File: newFileInfo(match.File), Snippet: match.Snippet, Score: match.Score})
+	}
+	return structuredResult(result)
+}
+
+func (t *fileTools) readFile(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filename, err := request.RequireString("filename")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	offset := int64(max(request.GetInt("offset", 0), 0))
+	length := int64(request.GetInt("length", defaultReadLength))
+	if length <= 0 || length > maxReadLength {
+		length = maxReadLength
+	}
+
+	file, err := t.store.GetFileMetadataByName(ctx, filename)
+	if err != nil || !t.permissions.canReadFile(ctx, file) {
+		return mcp.NewToolResultErrorf("file not found: %s", filename), nil
+	}
+	// a few more bytes than asked for are read so text can end on a whole character
+	var data []byte
+	var size int64
+	mimeType := file.MimeType
+	if rendition, err := t.store.GetRendition(ctx, file.ID); err == nil {
+		// documents are read through the text extracted from them, offsets count bytes of that text
+		data, size = renditionChunk(rendition.Content, offset, length+utf8.UTFMax)
+		mimeType = rendition.MimeType
+	} else {
+		total := float64(min(length+utf8.UTFMax, max(file.FileSize-offset, 0)))
+		data, size, err = fileio.ReadRange(ctx, file.FilePath, offset, length+utf8.UTFMax, func(read int64) {
+			provider.Progress(ctx, float64(read), total, "")
+		})
+		if ctx.Err() != nil {
+			return mcp.NewToolResultErrorf("read of %s was cancelled", filename), nil
+		}
+		if err != nil {
+			slog.Error("Failed to read file for tool", "filename", filename, "error", err)
+			return mcp.NewToolResultErrorf("could not read file: %s", filename), nil
+		}
+	}
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	var content mcp.Content
+	if extract.IsText(mimeType) {
+		start, end := textChunk(data, length, offset+int64(len(data)) == size)
+		data, offset = data[start:end], offset+start
+		content = mcp.NewTextContent(string(data))
+	} else {
+		data = data[:min(int64(len(data)), length)]
+		content = mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+			URI:      fileResourceURI(file.Filename),
+			MIMEType: mimeType,
+			Blob:     base64.StdEncoding.EncodeToString(data),
+		})
+	}
+	end := offset + int64(len(data))
+	result := &mcp.CallToolResult{Content: []mcp.Content{content}}
+	if end < size {
+		notifyClient(ctx, mcp.LoggingLevelNotice, provider.EventTruncated, fmt.Sprintf("read_file returned bytes %d-%d of %d of %s", offset, end, size, filename),
+			"filename", filename, "offset", offset, "end", end, "size", size)
+		result.Content = append(result.Content, mcp.NewTextContent(fmt.Sprintf(
+			"[showing bytes %d-%d of %d, call read_file with offset=%d to continue]", offset, end, size, end)))
+	}
+	return result, nil
+}
+
+// renditionChunk returns up to length bytes of a rendition starting at offset, with the size of the whole rendition
+func renditionChunk(content string, offset, length int64) ([]byte, int64) {
+	size := int64(len(content))
+	if offset >= size {
+		return []byte{}, size
+	}
+	return []byte(content[offset:min(offset+length, size)]), size
+}
+
+// textChunk returns where a chunk of text should start and end so it holds whole UTF-8 characters: after the
+// continuation bytes of a character begun before it, and before the first character that does not fit in length,
+// but after at least one character. data may hold a few more bytes than length, last is set when it ends the text
+func textChunk(data []byte, length int64, last bool) (int64, int64) {
+	var start int64
+	for start < int64(len(data)) && start < utf8.UTFMax-1 && !utf8.RuneStart(data[start]) {
+		start++
+	}
+	size := int64(len(data))
+	end := min(start+length, size)
+	if end == size {
+		if !last {
+			// the text goes on past data, drop a character it cuts
+			for i := end - 1; i >= start && i >= end-utf8.UTFMax; i-- {
+				if utf8.RuneStart(data[i]) {
+					if !utf8.FullRune(data[i:end]) {
+						end = i
+					}
+					break
+				}
+			}
+		}
+	} else {
+		for end > start && !utf8.RuneStart(data[end]) {
+			end--
+		}
+	}
+	if end == start && start < size {
+		// length is shorter than the first character, which is returned whole
+		_, size := utf8.DecodeRune(data[start:])
+		end = start + int64(size)
+	}
+	return start, end
+}
+
+// clampLimit falls back to the default for non-positive limits and caps the others at maxListLimit
+func clampLimit(limit, fallback int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	return min(limit, maxListLimit)
+}
+
+// structuredResult returns a value as the structured content of the tool result, matching the output schema of the
+// tool, and as indented JSON text for clients that do not read structured content
+func structuredResult(v any) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultStructured(v, string(data)), nil
+}
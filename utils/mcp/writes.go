@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aaryansinhaa/panes/utils/extract"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/aaryansinhaa/panes/utils/uploads"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tools writing files, the MCP server refreshes its file resources after their successful calls
+const (
+	toolCreateFile = "create_file"
+	toolUpdateFile = "update_file"
+	toolAppendFile = "append_file"
+)
+
+// maxWriteLength caps the content of one write, like the upload form, and what appends may grow a file to
+const maxWriteLength = 10 << 20 // 10 MB
+
+func (t *fileTools) createFile(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filename, content, result := writeArguments(request)
+	if result != nil {
+		return result, nil
+	}
+	if uploads.SanitizeFilename(filename) != filename {
+		return mcp.NewToolResultErrorf("invalid filename %q, use a name without directories or spaces such as %q", filename, uploads.SanitizeFilename(filename)), nil
+	}
+	// a client that may not create files learns nothing about which exist
+	if !t.permissions.canWriteFile(ctx, types.FileMetadata{}) {
+		t.permissions.refused(ctx, resourceFile, actionWrite, "")
+		return mcp.NewToolResultError("permission denied: creating files"), nil
+	}
+	if _, err := t.store.GetFileMetadataByName(ctx, filename); err == nil {
+		return mcp.NewToolResultErrorf("file already exists: %s, use %s or %s to change it", filename, toolUpdateFile, toolAppendFile), nil
+	}
+
+	file := types.FileMetadata{
+		Filename:     filename,
+		OriginalName: filename,
+		FilePath:     uploads.Path(filename),
+		MimeType:     extract.DetectMimeType(filename, "", []byte(content)),
+		Owner:        caller.ClientName,
+	}
+	if !extract.IsText(file.MimeType) {
+		return mcp.NewToolResultErrorf("only text files can be written, %s would be %s", filename, file.MimeType), nil
+	}
+	return t.write(ctx, caller, file, content, os.O_WRONLY|os.O_CREATE|os.O_EXCL, toolCreateFile)
+}
+
+func (t *fileTools) updateFile(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	file, content, result := t.writableFile(ctx, request)
+	if result != nil {
+		return result, nil
+	}
+	return t.write(ctx, caller, file, content, os.O_WRONLY|os.O_TRUNC, toolUpdateFile)
+}
+
+func (t *fileTools) appendFile(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	file, content, result := t.writableFile(ctx, request)
+	if result != nil {
+		return result, nil
+	}
+	return t.write(ctx, caller, file, content, os.O_WRONLY|os.O_APPEND, toolAppendFile)
+}
+
+// writeArguments reads the filename and content of a write, or returns the error result to answer with
+func writeArguments(request mcp.CallToolRequest) (string, string, *mcp.CallToolResult) {
+	filename, err := request.RequireString("filename")
+	if err != nil {
+		return "", "", mcp.NewToolResultError(err.Error())
+	}
+	content, err := request.RequireString("content")
+	if err != nil {
+		return "", "", mcp.NewToolResultError(err.Error())
+	}
+	if len(content) > maxWriteLength {
+		return "", "", mcp.NewToolResultErrorf("content is larger than %d bytes", maxWriteLength)
+	}
+	return filename, content, nil
+}
+
+// writableFile looks up the existing text file a write changes, or returns the error result to answer with
+func (t *fileTools) writableFile(ctx context.Context, request mcp.CallToolRequest) (types.FileMetadata, string, *mcp.CallToolResult) {
+	filename, content, result := writeArguments(request)
+	if result != nil {
+		return types.FileMetadata{}, "", result
+	}
+	file, err := t.store.GetFileMetadataByName(ctx, filename)
+	if err != nil || !t.permissions.canReadFile(ctx, file) {
+		return file, "", mcp.NewToolResultErrorf("file not found: %s", filename)
+	}
+	if !t.permissions.canWriteFile(ctx, file) {
+		t.permissions.refused(ctx, resourceFile, actionWrite, file.Filename)
+		return file, "", mcp.NewToolResultErrorf("permission denied: writing %s", filename)
+	}
+	if !extract.IsText(file.MimeType) {
+		return file, "", mcp.NewToolResultErrorf("only text files can be written, %s is %s", filename, file.MimeType)
+	}
+	return file, content, nil
+}
+
+// write stores content in the file the way an upload does: on disk, in the files table, in the content index and the
+// logs, then returns the file's metadata. Writes to one file run one at a time, uploads included. A cancelled call is
+// refused before anything is written, once writing started it runs to the end so the file and its index agree
+func (t *fileTools) write(ctx context.Context, caller provider.Caller, file types.FileMetadata, content string, flag int, action string) (*mcp.CallToolResult, error) {
+	log := types.LogEntry{Action: action, ClientName: caller.ClientName, Target: file.Filename}
+	fail := func(message string, err error) (*mcp.CallToolResult, error) {
+		slog.Error(message, "filename", file.Filename, "error", err)
+		log.Type = "error"
+		log.Message = message + ": " + err.Error()
+		if err := t.store.CreateLogEntry(log); err != nil {
+			slog.Error("Failed to log error", "error", err)
+		}
+		return mcp.NewToolResultErrorf("could not write file: %s", file.Filename), nil
+	}
+
+	defer uploads.Lock(file.Filename)()
+	if ctx.Err() != nil {
+		return mcp.NewToolResultErrorf("write of %s was cancelled", file.Filename), nil
+	}
+	if flag&os.O_APPEND != 0 {
+		info, err := os.Stat(file.FilePath)
+		if err != nil {
+			return fail("Failed to read file size", err)
+		}
+		if info.Size()+int64(len(content)) > maxWriteLength {
+			return mcp.NewToolResultErrorf("%s would grow past %d bytes, it is %d bytes already", file.Filename, maxWriteLength, info.Size()), nil
+		}
+	}
+	provider.Progress(ctx, 0, 2, "writing "+file.Filename)
+	if err := os.MkdirAll(uploads.Dir, os.ModePerm); err != nil {
+		return fail("Failed to create upload directory", err)
+	}
+	f, err := os.OpenFile(file.FilePath, flag, 0o644)
+	if err != nil {
+		return fail("Failed to open file for writing", err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return fail("Failed to write file", err)
+	}
+	if err := f.Close(); err != nil {
+		return fail("Failed to write file", err)
+	}
+	info, err := os.Stat(file.FilePath)
+	if err != nil {
+		return fail("Failed to write file", err)
+	}
+	file.FileSize = info.Size()
+
+	if action == toolCreateFile {
+		err = t.store.UploadFileMetadata(file)
+	} else {
+		err = t.store.UpdateFileMetadata(file)
+	}
+	if err != nil {
+		if action == toolCreateFile {
+			os.Remove(file.FilePath)
+		}
+		return fail("Failed to save file metadata", err)
+	}
+	provider.Progress(ctx, 1, 2, "indexing the text of "+file.Filename)
+	uploads.StoreText(context.WithoutCancel(ctx), t.store, t.index, file.Filename)
+	provider.Progress(ctx, 2, 2, "")
+
+	log.Type = "success"
+	log.Message = fmt.Sprintf("File written by %s via %s: %s (%d bytes)", caller.ClientName, action, file.Filename, file.FileSize)
+	if err := t.store.CreateLogEntry(log); err != nil {
+		slog.Error("Failed to log success", "error", err)
+	}
+
+	written, err := t.store.GetFileMetadataByName(ctx, file.Filename)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("file was written but could not be read back", err), nil
+	}
+	return structuredResult(newFileInfo(written))
+}
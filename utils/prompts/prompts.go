@@ -0,0 +1,86 @@
+package prompts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/gateway"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// Argument types a prompt template can declare
+const (
+	ArgumentString  = "string"
+	ArgumentNumber  = "number"
+	ArgumentBoolean = "boolean"
+)
+
+// placeholder matches {{argument_name}}, allowing spaces inside the braces
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Validate checks that a prompt has a name and a template, that its arguments are well typed and unique,
+// and that every placeholder in the template refers to a declared argument
+func Validate(prompt types.Prompt) error {
+	if strings.TrimSpace(prompt.Name) == "" {
+		return fmt.Errorf("prompt name is required")
+	}
+	if strings.Contains(prompt.Name, gateway.Separator) {
+		return fmt.Errorf("prompt name must not contain %q, it is kept for the prompts of downstream servers", gateway.Separator)
+	}
+	if strings.TrimSpace(prompt.Template) == "" {
+		return fmt.Errorf("prompt template is required")
+	}
+
+	declared := make(map[string]bool, len(prompt.Arguments))
+	for _, argument := range prompt.Arguments {
+		if argument.Name == "" {
+			return fmt.Errorf("prompt argument name is required")
+		}
+		if declared[argument.Name] {
+			return fmt.Errorf("prompt argument %q is declared twice", argument.Name)
+		}
+		switch argument.Type {
+		case "", ArgumentString, ArgumentNumber, ArgumentBoolean:
+		default:
+			return fmt.Errorf("prompt argument %q has unknown type %q", argument.Name, argument.Type)
+		}
+		declared[argument.Name] = true
+	}
+
+	for _, match := range placeholder.FindAllStringSubmatch(prompt.Template, -1) {
+		if !declared[match[1]] {
+			return fmt.Errorf("template placeholder {{%s}} is not a declared argument", match[1])
+		}
+	}
+	return nil
+}
+
+// Render substitutes the given argument values into the prompt template, checking required arguments and types.
+// Optional arguments that were not given are substituted with an empty string
+func Render(prompt types.Prompt, values map[string]string) (string, error) {
+	for _, argument := range prompt.Arguments {
+		value, ok := values[argument.Name]
+		if !ok || value == "" {
+			if argument.Required {
+				return "", fmt.Errorf("missing required argument %q", argument.Name)
+			}
+			continue
+		}
+		switch argument.Type {
+		case ArgumentNumber:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return "", fmt.Errorf("argument %q must be a number", argument.Name)
+			}
+		case ArgumentBoolean:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return "", fmt.Errorf("argument %q must be a boolean", argument.Name)
+			}
+		}
+	}
+
+	return placeholder.ReplaceAllStringFunc(prompt.Template, func(match string) string {
+		return values[placeholder.FindStringSubmatch(match)[1]]
+	}), nil
+}
@@ -1,26 +1,33 @@
 package file
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/aaryansinhaa/panes/utils/embeddings"
+	"github.com/aaryansinhaa/panes/utils/extract"
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers"
+	"github.com/aaryansinhaa/panes/utils/services/interfaces"
 	"github.com/aaryansinhaa/panes/utils/services/storage"
 	"github.com/aaryansinhaa/panes/utils/types"
+	"github.com/aaryansinhaa/panes/utils/uploads"
 )
 
-func sanitizeFilename(name string) string {
-	name = filepath.Base(name)
-	name = strings.ReplaceAll(name, " ", "_")
-	return name
-}
+const (
+	defaultContentSearchLimit = 20
+	maxContentSearchLimit     = 200
+)
 
-// FileUploadHandler handles file uploads
-func FileUploadHandler(w http.ResponseWriter, r *http.Request, store *storage.SQLite) {
+// FileUploadHandler handles file uploads, an upload with the name of an existing file replaces its content
+func FileUploadHandler(w http.ResponseWriter, r *http.Request, store *storage.SQLite, notifier interfaces.ResourceNotifier, index *embeddings.Index) {
 	slog.Info("uploading File")
 	var log types.LogEntry
 	r.ParseMultipartForm(10 << 20) // 10 MB limit
@@ -58,12 +65,28 @@ func FileUploadHandler(w http.ResponseWriter, r *http.Request, store *storage.SQ
 	var fileMetaData types.FileMetadata
 	fileMetaData.Filename = handler.Filename
 	fileMetaData.OriginalName = handler.Filename
-	fileMetaData.FilePath = filepath.Join("./uploads", sanitizeFilename(handler.Filename))
-	fileMetaData.MimeType = handler.Header.Get("Content-Type")
+	fileMetaData.FilePath = uploads.Path(handler.Filename)
+	// browsers often send a generic type for documents, the extension and content tell what the file really is
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		slog.Error("Failed to rewind uploaded file", "error", err)
+		http.Error(w, "Error reading the uploaded file", http.StatusInternalServerError)
+		return
+	}
+	fileMetaData.MimeType = extract.DetectMimeType(handler.Filename, handler.Header.Get("Content-Type"), head[:n])
 	fileMetaData.FileSize = handler.Size
 	fileMetaData.Owner = "system" // default owner, can be changed later
-	// Save file metadata to the database
-	err = store.UploadFileMetadata(fileMetaData)
+	// the file, its metadata and its text are written under the lock MCP writes to the file take too
+	defer uploads.Lock(handler.Filename)()
+	// Save file metadata to the database, updating it when the file is being replaced
+	_, lookupErr := store.GetFileMetadataByName(r.Context(), fileMetaData.Filename)
+	replaced := lookupErr == nil
+	if replaced {
+		err = store.UpdateFileMetadata(fileMetaData)
+	} else {
+		err = store.UploadFileMetadata(fileMetaData)
+	}
 
 	if err != nil {
 		slog.Error("Failed to upload file metadata", "error", err)
@@ -78,13 +101,13 @@ func FileUploadHandler(w http.ResponseWriter, r *http.Request, store *storage.SQ
 		}
 		return
 	}
-	safeFilename := sanitizeFilename(handler.Filename)
+	safeFilename := uploads.SanitizeFilename(handler.Filename)
 	slog.Info("uploaded file", "filename", safeFilename)
 	slog.Info("file size", "size", handler.Size)
-	slog.Info("file type", "type", handler.Header.Get("Content-Type"))
+	slog.Info("file type", "type", fileMetaData.MimeType)
 	slog.Info("Mime Header", "header", handler.Header)
 
-	uploadDir := "./uploads"
+	uploadDir := uploads.Dir
 	os.MkdirAll(uploadDir, os.ModePerm)
 
 	dstPath := filepath.Join(uploadDir, safeFilename)
@@ -120,6 +143,13 @@ func FileUploadHandler(w http.ResponseWriter, r *http.Request, store *storage.SQ
 	}
 
 	slog.Info("File uploaded successfully", "filename", safeFilename)
+	// the file is saved, its text is indexed even when the uploader hangs up
+	uploads.StoreText(context.WithoutCancel(r.Context()), store, index, fileMetaData.Filename)
+	if replaced {
+		notifier.FileUpdated(fileMetaData.Filename)
+	} else {
+		notifier.FilesChanged()
+	}
 	log.Message = "File uploaded successfully: " + safeFilename
 	log.Type = "success"
 	log.Action = "upload"
@@ -135,6 +165,43 @@ func FileUploadHandler(w http.ResponseWriter, r *http.Request, store *storage.SQ
 	json.NewEncoder(w).Encode(map[string]string{"message": "File uploaded and saved successfully", "filename": safeFilename})
 }
 
+// DownloadFileHandler serves the original bytes of an uploaded file, MCP clients get its extracted text instead
+func DownloadFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	fileName := r.PathValue("filename")
+	if fileName == "" {
+		http.Error(w, "No filename provided", http.StatusBadRequest)
+		return
+	}
+	file, err := s.GetFileMetadataByName(r.Context(), fileName)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		slog.Error("Failed to open file for download", "filename", fileName, "error", err)
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Could not read the file", http.StatusInternalServerError)
+		return
+	}
+
+	if file.MimeType != "" {
+		w.Header().Set("Content-Type", file.MimeType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.OriginalName}))
+	http.ServeContent(w, r, file.Filename, info.ModTime(), f)
+
+	err = s.CreateLogEntry(types.LogEntry{Message: "File downloaded: " + file.Filename, Type: "success", Action: "download", ClientName: "admin"})
+	if err != nil {
+		slog.Error("Failed to log success", "error", err)
+	}
+}
+
 // ListFilesHandler lists uploaded files
 func ListFilesHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
 	slog.Info("listing files")
@@ -142,7 +209,7 @@ func ListFilesHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite)
 	var log types.LogEntry
 
 	var fileList []types.FileMetadata
-	fileList, err := s.ListFileMetadata()
+	fileList, err := s.ListFileMetadata(r.Context())
 	if err != nil {
 		slog.Error("Failed to list file metadata", "error", err)
 		http.Error(w, "Could not retrieve file metadata", http.StatusInternalServerError)
@@ -181,7 +248,7 @@ func ListFilesHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite)
 	}
 }
 
-func DeleteFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+func DeleteFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, notifier interfaces.ResourceNotifier) {
 	slog.Info("deleting file")
 
 	var log types.LogEntry
@@ -201,8 +268,8 @@ func DeleteFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite
 		}
 		return
 	}
-	safeFilename := sanitizeFilename(fileName)
-	filePath := filepath.Join("./uploads", safeFilename)
+	safeFilename := uploads.SanitizeFilename(fileName)
+	filePath := filepath.Join(uploads.Dir, safeFilename)
 
 	// Check if file exists before deleting
 	if info, err := os.Stat(filePath); os.IsNotExist(err) || info.IsDir() {
@@ -255,6 +322,7 @@ func DeleteFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite
 	}
 
 	slog.Info("File deleted successfully", "filename", safeFilename)
+	notifier.FilesChanged()
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -273,6 +341,82 @@ func DeleteFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite
 	}
 }
 
+// SearchContentHandler searches the text of the uploaded files, best matches first, with highlighted snippets
+func SearchContentHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit, ok := handlers.QueryLimit(w, r, defaultContentSearchLimit, maxContentSearchLimit)
+	if !ok {
+		return
+	}
+
+	log := types.LogEntry{Action: "search_content", ClientName: "admin"}
+	matches, err := s.SearchFileContent(r.Context(), "", query, limit)
+	if errors.Is(err, storage.ErrContentSearchUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not search file contents", http.StatusInternalServerError)
+		log.Message = "Failed to search file contents: " + err.Error()
+		log.Type = "error"
+		if err := s.CreateLogEntry(log); err != nil {
+			slog.Error("Failed to log error", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"query": query, "results": matches})
+	log.Message = "Searched file contents for: " + query
+	log.Type = "success"
+	if err := s.CreateLogEntry(log); err != nil {
+		slog.Error("Failed to log success", "error", err)
+	}
+}
+
+// SemanticSearchHandler searches the text of the uploaded files by meaning, closest first
+func SemanticSearchHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, index *embeddings.Index) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	limit, ok := handlers.QueryLimit(w, r, defaultContentSearchLimit, maxContentSearchLimit)
+	if !ok {
+		return
+	}
+
+	log := types.LogEntry{Action: "semantic_search", ClientName: "admin"}
+	matches, err := index.Search(r.Context(), "", query, limit)
+	if errors.Is(err, embeddings.ErrNotConfigured) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not search file contents", http.StatusBadGateway)
+		log.Message = "Failed to search file contents by meaning: " + err.Error()
+		log.Type = "error"
+		if err := s.CreateLogEntry(log); err != nil {
+			slog.Error("Failed to log error", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"query": query, "results": matches})
+	log.Message = "Searched file contents by meaning for: " + query
+	log.Type = "success"
+	if err := s.CreateLogEntry(log); err != nil {
+		slog.Error("Failed to log success", "error", err)
+	}
+}
+
 // search for FileMetadata by filename
 func SearchFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
 	fileName := r.PathValue("filename")
@@ -291,8 +435,8 @@ func SearchFileHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite
 		}
 		return
 	}
-	safeFilename := sanitizeFilename(fileName)
-	fileMetadata, err := s.SearchFilesByName(safeFilename, 1)
+	safeFilename := uploads.SanitizeFilename(fileName)
+	fileMetadata, err := s.SearchFilesByName(r.Context(), "", safeFilename, 1)
 	if err != nil {
 		slog.Error("Failed to search file metadata", "error", err)
 		http.Error(w, "Could not retrieve file metadata", http.StatusInternalServerError)
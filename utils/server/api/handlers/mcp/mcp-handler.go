@@ -1,16 +1,244 @@
 package mcp
 
-import "net/http"
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-func RegisterClientHandler(w http.ResponseWriter, r *http.Request) {
-	
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers"
+	"github.com/aaryansinhaa/panes/utils/services/interfaces"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+const defaultClientListLimit = 100
+
+// registeredClient is returned whenever a new API key is issued, the key itself is never stored or shown again
+type registeredClient struct {
+	ClientID   string `json:"client_id"`
+	ClientName string `json:"client_name"`
+	APIKey     string `json:"api_key"`
+}
+
+// withUsage adds to a client how many calls it made today and what is left of its daily quotas
+func withUsage(s *storage.SQLite, client *types.Client) error {
+	day := time.Now().UTC().Format(time.DateOnly)
+	calls, err := s.GetClientUsage(client.ClientID, day)
+	if err != nil {
+		return err
+	}
+	usage := &types.ClientUsage{Day: day, Calls: quotaUsage(calls[""], client.DailyQuota)}
+	for tool, limits := range client.ToolLimits {
+		if usage.Tools == nil {
+			usage.Tools = make(map[string]types.QuotaUsage)
+		}
+		usage.Tools[tool] = quotaUsage(calls[tool], limits.DailyQuota)
+	}
+	client.Usage = usage
+	return nil
+}
+
+func quotaUsage(used, quota int64) types.QuotaUsage {
+	usage := types.QuotaUsage{Used: used}
+	if quota > 0 {
+		remaining := max(quota-used, 0)
+		usage.Remaining = &remaining
+	}
+	return usage
 }
 
-func ListClientsHandler(w http.ResponseWriter, r *http.Request) {
+// validLimits reports whether limits are usable, negative values are refused
+func validLimits(limits types.ClientLimits) bool {
+	return limits.RateLimit >= 0 && limits.Burst >= 0 && limits.DailyQuota >= 0
 }
 
-func DeleteClientHandler(w http.ResponseWriter, r *http.Request) {
+// RegisterClientHandler registers a new MCP client and returns its API key
+func RegisterClientHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	var body struct {
+		ClientName string `json:"client_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.ClientName) == "" {
+		http.Error(w, "client_name is required", http.StatusBadRequest)
+		return
+	}
+
+	clientID, err := auth.NewClientID()
+	if err != nil {
+		http.Error(w, "Could not generate client ID", http.StatusInternalServerError)
+		return
+	}
+	apiKey, err := auth.GenerateAPIKey()
+	if err != nil {
+		http.Error(w, "Could not generate API key", http.StatusInternalServerError)
+		return
+	}
+	if err := s.CreateClient(clientID, body.ClientName, auth.HashAPIKey(apiKey)); err != nil {
+		http.Error(w, "Could not register client", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to register client: "+err.Error(), "error", "register_client")
+		return
+	}
+	handlers.LogAdminAction(s, "Client registered successfully: "+body.ClientName+" ("+clientID+")", "success", "register_client")
+
+	handlers.WriteJSON(w, http.StatusCreated, registeredClient{ClientID: clientID, ClientName: body.ClientName, APIKey: apiKey})
 }
 
-func UpdateClientHandler(w http.ResponseWriter, r *http.Request) {
+// ListClientsHandler lists the registered MCP clients with their usage of today, without their API key hashes
+func ListClientsHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	limit := defaultClientListLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	clients, err := s.GetClients(limit)
+	if err != nil {
+		http.Error(w, "Could not retrieve clients", http.StatusInternalServerError)
+		return
+	}
+	for i := range clients {
+		clients[i].ClientAPIHash = ""
+		if err := withUsage(s, &clients[i]); err != nil {
+			http.Error(w, "Could not retrieve client usage", http.StatusInternalServerError)
+			return
+		}
+	}
+	if clients == nil {
+		clients = []types.Client{}
+	}
+	handlers.WriteJSON(w, http.StatusOK, map[string][]types.Client{"clients": clients})
+}
+
+// DeleteClientHandler deletes an MCP client, its API key stops working immediately
+func DeleteClientHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	clientID := r.PathValue("clientId")
+	if clientID == "" {
+		http.Error(w, "No client ID provided", http.StatusBadRequest)
+		return
+	}
+
+	err := s.DeleteClient(clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not delete client", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to delete client: "+err.Error(), "error", "delete_client")
+		return
+	}
+	handlers.LogAdminAction(s, "Client deleted successfully: "+clientID, "success", "delete_client")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateClientHandler renames, activates or deactivates an MCP client, sets its rate limits and daily quotas and can rotate its API key.
+// New limits start from full buckets
+func UpdateClientHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, limiter interfaces.RateLimiter) {
+	clientID := r.PathValue("clientId")
+	if clientID == "" {
+		http.Error(w, "No client ID provided", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		ClientName string                         `json:"client_name"`
+		Active     *bool                          `json:"active"`
+		RotateKey  bool                           `json:"rotate_key"`
+		RateLimit  *float64                       `json:"rate_limit"`
+		Burst      *int                           `json:"burst"`
+		DailyQuota *int64                         `json:"daily_quota"`
+		ToolLimits *map[string]types.ClientLimits `json:"tool_limits"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid client body", http.StatusBadRequest)
+		return
+	}
+
+	client, err := s.GetClientByID(clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve client", http.StatusInternalServerError)
+		return
+	}
+	if strings.TrimSpace(body.ClientName) != "" {
+		client.ClientName = body.ClientName
+	}
+
+	limitsChanged := body.RateLimit != nil || body.Burst != nil || body.DailyQuota != nil || body.ToolLimits != nil
+	if limitsChanged {
+		if body.RateLimit != nil {
+			client.RateLimit = *body.RateLimit
+		}
+		if body.Burst != nil {
+			client.Burst = *body.Burst
+		}
+		if body.DailyQuota != nil {
+			client.DailyQuota = *body.DailyQuota
+		}
+		if body.ToolLimits != nil {
+			client.ToolLimits = *body.ToolLimits
+		}
+		valid := validLimits(client.ClientLimits)
+		for _, limits := range client.ToolLimits {
+			valid = valid && validLimits(limits)
+		}
+		if !valid {
+			http.Error(w, "Limits must not be negative", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var apiKey, apiHash string
+	if body.RotateKey {
+		apiKey, err = auth.GenerateAPIKey()
+		if err != nil {
+			http.Error(w, "Could not generate API key", http.StatusInternalServerError)
+			return
+		}
+		apiHash = auth.HashAPIKey(apiKey)
+	}
+	if err := s.UpdateClient(clientID, client.ClientName, apiHash); err != nil {
+		http.Error(w, "Could not update client", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to update client: "+err.Error(), "error", "update_client")
+		return
+	}
+	if body.Active != nil {
+		if err := s.SetClientActive(clientID, *body.Active); err != nil {
+			http.Error(w, "Could not update client status", http.StatusInternalServerError)
+			handlers.LogAdminAction(s, "Failed to update client status: "+err.Error(), "error", "update_client")
+			return
+		}
+		client.Active = *body.Active
+	}
+	if limitsChanged {
+		if err := s.SetClientLimits(clientID, client.ClientLimits, client.ToolLimits); err != nil {
+			http.Error(w, "Could not update client limits", http.StatusInternalServerError)
+			handlers.LogAdminAction(s, "Failed to update client limits: "+err.Error(), "error", "update_client")
+			return
+		}
+		limiter.ResetRateLimits(clientID)
+	}
+	handlers.LogAdminAction(s, "Client updated successfully: "+clientID, "success", "update_client")
+
+	if body.RotateKey {
+		handlers.WriteJSON(w, http.StatusOK, registeredClient{ClientID: clientID, ClientName: client.ClientName, APIKey: apiKey})
+		return
+	}
+	client.ClientAPIHash = ""
+	if err := withUsage(s, &client); err != nil {
+		http.Error(w, "Could not retrieve client usage", http.StatusInternalServerError)
+		return
+	}
+	handlers.WriteJSON(w, http.StatusOK, client)
 }
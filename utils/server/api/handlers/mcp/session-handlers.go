@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"errors"
+	"net/http"
+
+	mcpserver "github.com/aaryansinhaa/panes/utils/mcp"
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers"
+	"github.com/aaryansinhaa/panes/utils/services/interfaces"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// ListSessionsHandler lists the connected MCP sessions
+func ListSessionsHandler(w http.ResponseWriter, r *http.Request, registry interfaces.SessionRegistry) {
+	handlers.WriteJSON(w, http.StatusOK, map[string][]types.MCPSession{"sessions": registry.Sessions()})
+}
+
+// TerminateSessionHandler forcibly ends a connected MCP session
+func TerminateSessionHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, registry interfaces.SessionRegistry) {
+	sessionID := r.PathValue("sessionId")
+	if sessionID == "" {
+		http.Error(w, "No session ID provided", http.StatusBadRequest)
+		return
+	}
+
+	err := registry.TerminateSession(sessionID)
+	if errors.Is(err, mcpserver.ErrSessionNotFound) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, mcpserver.ErrSessionNotTerminable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not terminate session", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to terminate session "+sessionID+": "+err.Error(), "error", "terminate_session")
+		return
+	}
+	handlers.LogAdminAction(s, "Session terminated successfully: "+sessionID, "success", "terminate_session")
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,124 @@
+package permissions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// decodePermission reads a permission from the request body, checks its client exists
+// and resolves a file grant given by filename to the file's ID
+func decodePermission(w http.ResponseWriter, r *http.Request, s *storage.SQLite) (types.Permission, bool) {
+	var permission types.Permission
+	if err := json.NewDecoder(r.Body).Decode(&permission); err != nil {
+		http.Error(w, "Invalid permission body", http.StatusBadRequest)
+		return permission, false
+	}
+	if id := r.PathValue("clientId"); id != "" {
+		permission.ClientID = id
+	}
+	permission.Resource = strings.ToLower(strings.TrimSpace(permission.Resource))
+	permission.Action = strings.ToLower(strings.TrimSpace(permission.Action))
+	if permission.ClientID == "" || permission.Resource == "" || permission.Action == "" {
+		http.Error(w, "client_id, resource and action are required", http.StatusBadRequest)
+		return permission, false
+	}
+	if _, err := s.GetClientByID(permission.ClientID); err != nil {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return permission, false
+	}
+
+	if permission.Resource == "file" && permission.ResourceID != "" {
+		if _, err := strconv.ParseInt(permission.ResourceID, 10, 64); err != nil {
+			file, err := s.GetFileMetadataByName(r.Context(), permission.ResourceID)
+			if err != nil {
+				http.Error(w, "File not found", http.StatusNotFound)
+				return permission, false
+			}
+			permission.ResourceID = strconv.FormatInt(file.ID, 10)
+		}
+	}
+	return permission, true
+}
+
+// CreatePermissionHandler grants a client an action on a resource, on one resource when resource_id is set
+func CreatePermissionHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	permission, ok := decodePermission(w, r, s)
+	if !ok {
+		return
+	}
+	if err := s.CreatePermission(permission.ClientID, permission.Resource, permission.Action, permission.ResourceID); err != nil {
+		http.Error(w, "Could not create permission", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to create permission: "+err.Error(), "error", "create_permission")
+		return
+	}
+	handlers.LogAdminAction(s, "Permission granted to "+permission.ClientID+": "+permission.Action+" on "+permission.Resource, "success", "create_permission")
+	writeClientPermissions(w, s, permission.ClientID, http.StatusCreated)
+}
+
+// ListPermissionsHandler lists the permissions granted to a client
+func ListPermissionsHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	clientID := r.PathValue("clientId")
+	if clientID == "" {
+		http.Error(w, "No client ID provided", http.StatusBadRequest)
+		return
+	}
+	writeClientPermissions(w, s, clientID, http.StatusOK)
+}
+
+// writeClientPermissions responds with every permission currently granted to a client
+func writeClientPermissions(w http.ResponseWriter, s *storage.SQLite, clientID string, status int) {
+	permissionList, err := s.GetPermissionsByClientID(clientID)
+	if err != nil {
+		http.Error(w, "Could not retrieve permissions", http.StatusInternalServerError)
+		return
+	}
+	if permissionList == nil {
+		permissionList = []types.Permission{}
+	}
+	handlers.WriteJSON(w, status, map[string][]types.Permission{"permissions": permissionList})
+}
+
+// UpdatePermissionHandler replaces every grant a client has on a resource kind with the given one
+func UpdatePermissionHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	permission, ok := decodePermission(w, r, s)
+	if !ok {
+		return
+	}
+	if err := s.UpdatePermissionByClientID(permission.ClientID, permission.Resource, permission.Action, permission.ResourceID); err != nil {
+		http.Error(w, "Could not update permissions", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to update permissions: "+err.Error(), "error", "update_permission")
+		return
+	}
+	handlers.LogAdminAction(s, "Permissions of "+permission.ClientID+" on "+permission.Resource+" replaced", "success", "update_permission")
+	writeClientPermissions(w, s, permission.ClientID, http.StatusOK)
+}
+
+// DeletePermissionHandler revokes a permission by ID
+func DeletePermissionHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid permission ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.DeletePermission(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Permission not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not delete permission", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to delete permission: "+err.Error(), "error", "delete_permission")
+		return
+	}
+	handlers.LogAdminAction(s, "Permission revoked: "+strconv.FormatInt(id, 10), "success", "delete_permission")
+	w.WriteHeader(http.StatusNoContent)
+}
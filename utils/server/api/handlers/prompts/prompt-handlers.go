@@ -0,0 +1,141 @@
+package prompts
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aaryansinhaa/panes/utils/prompts"
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers"
+	"github.com/aaryansinhaa/panes/utils/services/interfaces"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// CreatePromptHandler stores a new prompt template
+func CreatePromptHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, notifier interfaces.PromptNotifier) {
+	var prompt types.Prompt
+	if err := json.NewDecoder(r.Body).Decode(&prompt); err != nil {
+		http.Error(w, "Invalid prompt body", http.StatusBadRequest)
+		return
+	}
+	if err := prompts.Validate(prompt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := s.GetPromptByName(prompt.Name); err == nil {
+		http.Error(w, "A prompt with this name already exists", http.StatusConflict)
+		return
+	}
+
+	if err := s.CreatePrompt(prompt); err != nil {
+		http.Error(w, "Could not save prompt", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to create prompt: "+err.Error(), "error", "create_prompt")
+		return
+	}
+	notifier.PromptsChanged()
+	handlers.LogAdminAction(s, "Prompt created successfully: "+prompt.Name, "success", "create_prompt")
+
+	created, err := s.GetPromptByName(prompt.Name)
+	if err != nil {
+		http.Error(w, "Could not retrieve prompt", http.StatusInternalServerError)
+		return
+	}
+	handlers.WriteJSON(w, http.StatusCreated, created)
+}
+
+// ListPromptsHandler lists every stored prompt template
+func ListPromptsHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	promptList, err := s.ListPrompts()
+	if err != nil {
+		http.Error(w, "Could not retrieve prompts", http.StatusInternalServerError)
+		return
+	}
+	if promptList == nil {
+		promptList = []types.Prompt{}
+	}
+	handlers.WriteJSON(w, http.StatusOK, map[string][]types.Prompt{"prompts": promptList})
+}
+
+// GetPromptHandler returns a single prompt template by name
+func GetPromptHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite) {
+	name := r.PathValue("name")
+	if name == "" {
+		http.Error(w, "No prompt name provided", http.StatusBadRequest)
+		return
+	}
+	prompt, err := s.GetPromptByName(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not retrieve prompt", http.StatusInternalServerError)
+		return
+	}
+	handlers.WriteJSON(w, http.StatusOK, prompt)
+}
+
+// UpdatePromptHandler replaces the description, template and arguments of a prompt, bumping its version
+func UpdatePromptHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, notifier interfaces.PromptNotifier) {
+	name := r.PathValue("name")
+	if name == "" {
+		http.Error(w, "No prompt name provided", http.StatusBadRequest)
+		return
+	}
+	var prompt types.Prompt
+	if err := json.NewDecoder(r.Body).Decode(&prompt); err != nil {
+		http.Error(w, "Invalid prompt body", http.StatusBadRequest)
+		return
+	}
+	prompt.Name = name
+	if err := prompts.Validate(prompt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := s.UpdatePrompt(prompt)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not update prompt", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to update prompt: "+err.Error(), "error", "update_prompt")
+		return
+	}
+	notifier.PromptsChanged()
+	handlers.LogAdminAction(s, "Prompt updated successfully: "+name, "success", "update_prompt")
+
+	updated, err := s.GetPromptByName(name)
+	if err != nil {
+		http.Error(w, "Could not retrieve prompt", http.StatusInternalServerError)
+		return
+	}
+	handlers.WriteJSON(w, http.StatusOK, updated)
+}
+
+// DeletePromptHandler deletes a prompt template by name
+func DeletePromptHandler(w http.ResponseWriter, r *http.Request, s *storage.SQLite, notifier interfaces.PromptNotifier) {
+	name := r.PathValue("name")
+	if name == "" {
+		http.Error(w, "No prompt name provided", http.StatusBadRequest)
+		return
+	}
+
+	err := s.DeletePrompt(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not delete prompt", http.StatusInternalServerError)
+		handlers.LogAdminAction(s, "Failed to delete prompt: "+err.Error(), "error", "delete_prompt")
+		return
+	}
+	notifier.PromptsChanged()
+	handlers.LogAdminAction(s, "Prompt deleted successfully: "+name, "success", "delete_prompt")
+
+	w.WriteHeader(http.StatusNoContent)
+}
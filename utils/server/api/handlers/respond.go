@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// WriteJSON answers with v encoded as JSON and the given status
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write JSON response", "error", err)
+	}
+}
+
+// QueryLimit reads the limit query parameter, fallback when it is missing and at most maxLimit. A limit that is not
+// a positive number is answered with 400 and ok false
+func QueryLimit(w http.ResponseWriter, r *http.Request, fallback, maxLimit int) (limit int, ok bool) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return fallback, true
+	}
+	parsed, err := strconv.Atoi(l)
+	if err != nil || parsed <= 0 {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return 0, false
+	}
+	return min(parsed, maxLimit), true
+}
+
+// LogAdminAction records an action taken through the REST API
+func LogAdminAction(s *storage.SQLite, message, logType, action string) {
+	err := s.CreateLogEntry(types.LogEntry{Message: message, Type: logType, Action: action, ClientName: "admin"})
+	if err != nil {
+		slog.Error("Failed to log admin action", "action", action, "error", err)
+	}
+}
@@ -3,49 +3,144 @@ package api
 import (
 	"net/http"
 
+	"github.com/aaryansinhaa/panes/utils/auth"
+	"github.com/aaryansinhaa/panes/utils/embeddings"
 	"github.com/aaryansinhaa/panes/utils/server/api/handlers"
 	"github.com/aaryansinhaa/panes/utils/server/api/handlers/file"
 	"github.com/aaryansinhaa/panes/utils/server/api/handlers/logs"
 	"github.com/aaryansinhaa/panes/utils/server/api/handlers/mcp"
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers/permissions"
+	"github.com/aaryansinhaa/panes/utils/server/api/handlers/prompts"
+	"github.com/aaryansinhaa/panes/utils/services/interfaces"
 	"github.com/aaryansinhaa/panes/utils/services/storage"
 )
 
-func Router(s *storage.SQLite) *http.ServeMux {
+// MCPTransports holds the MCP transport handlers mounted next to the REST API, nil handlers are not mounted
+type MCPTransports struct {
+	StreamablePath string
+	Streamable     http.Handler
+	SSEPath        string
+	SSE            http.Handler
+	MessagePath    string
+	Message        http.Handler
+}
+
+// Router serves the REST API next to the MCP transports. The REST API needs adminKey as a bearer token, without one it
+// is only served when no MCP transport is mounted
+func Router(s *storage.SQLite, adminKey string, transports MCPTransports, notifier interfaces.Notifier, sessions interfaces.SessionRegistry, limiter interfaces.RateLimiter, index *embeddings.Index) *http.ServeMux {
 	router := http.NewServeMux()
 
+	//mcp transports
+	if transports.Streamable != nil {
+		router.Handle(transports.StreamablePath, transports.Streamable)
+	}
+	if transports.SSE != nil {
+		router.Handle("GET "+transports.SSEPath, transports.SSE)
+	}
+	if transports.Message != nil {
+		router.Handle("POST "+transports.MessagePath, transports.Message)
+	}
+
 	//general routing
 	router.HandleFunc("GET /api", handlers.IndexHandler)
-	router.HandleFunc("GET /api/logs/{limit}", func(w http.ResponseWriter, r *http.Request) {
+
+	//admin services, everything under /api/
+	admin := http.NewServeMux()
+	admin.HandleFunc("GET /api/logs/{limit}", func(w http.ResponseWriter, r *http.Request) {
 		logs.ListLogHandler(w, r, s)
 	})
-	router.HandleFunc("DELETE /api/logs/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+	admin.HandleFunc("DELETE /api/logs/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
 		logs.DeleteLogEntryHandler(w, r, s)
 	})
-	router.HandleFunc("DELETE /api/logs/delete/all", func(w http.ResponseWriter, r *http.Request) {
+	admin.HandleFunc("DELETE /api/logs/delete/all", func(w http.ResponseWriter, r *http.Request) {
 		logs.DeleteAllLogEntriesHandler(w, r, s)
 	})
 
 	//file based services
-	router.HandleFunc("POST /api/files/upload", func(w http.ResponseWriter, r *http.Request) {
-		file.FileUploadHandler(w, r, s)
+	admin.HandleFunc("POST /api/files/upload", func(w http.ResponseWriter, r *http.Request) {
+		file.FileUploadHandler(w, r, s, notifier, index)
 	})
-	router.HandleFunc("GET /api/files/list", func(w http.ResponseWriter, r *http.Request) {
+	admin.HandleFunc("GET /api/files/list", func(w http.ResponseWriter, r *http.Request) {
 		file.ListFilesHandler(w, r, s)
 	})
-	router.HandleFunc("GET /api/files/list/{filename}", func(w http.ResponseWriter, r *http.Request) {
+	admin.HandleFunc("GET /api/files/list/{filename}", func(w http.ResponseWriter, r *http.Request) {
 		file.SearchFileHandler(w, r, s)
 	})
-	router.HandleFunc("DELETE /api/files/delete/{filename}", func(w http.ResponseWriter, r *http.Request) {
-		file.DeleteFileHandler(w, r, s)
+	admin.HandleFunc("GET /api/files/search", func(w http.ResponseWriter, r *http.Request) {
+		file.SearchContentHandler(w, r, s)
+	})
+	admin.HandleFunc("GET /api/files/search/semantic", func(w http.ResponseWriter, r *http.Request) {
+		file.SemanticSearchHandler(w, r, s, index)
+	})
+	admin.HandleFunc("GET /api/files/download/{filename}", func(w http.ResponseWriter, r *http.Request) {
+		file.DownloadFileHandler(w, r, s)
+	})
+	admin.HandleFunc("DELETE /api/files/delete/{filename}", func(w http.ResponseWriter, r *http.Request) {
+		file.DeleteFileHandler(w, r, s, notifier)
+	})
+
+	//prompt library
+	admin.HandleFunc("POST /api/prompts/create", func(w http.ResponseWriter, r *http.Request) {
+		prompts.CreatePromptHandler(w, r, s, notifier)
+	})
+	admin.HandleFunc("GET /api/prompts/list", func(w http.ResponseWriter, r *http.Request) {
+		prompts.ListPromptsHandler(w, r, s)
+	})
+	admin.HandleFunc("GET /api/prompts/list/{name}", func(w http.ResponseWriter, r *http.Request) {
+		prompts.GetPromptHandler(w, r, s)
+	})
+	admin.HandleFunc("PUT /api/prompts/update/{name}", func(w http.ResponseWriter, r *http.Request) {
+		prompts.UpdatePromptHandler(w, r, s, notifier)
+	})
+	admin.HandleFunc("DELETE /api/prompts/delete/{name}", func(w http.ResponseWriter, r *http.Request) {
+		prompts.DeletePromptHandler(w, r, s, notifier)
 	})
 
 	//permission based services
+	admin.HandleFunc("POST /api/permissions/create", func(w http.ResponseWriter, r *http.Request) {
+		permissions.CreatePermissionHandler(w, r, s)
+	})
+	admin.HandleFunc("GET /api/permissions/list/{clientId}", func(w http.ResponseWriter, r *http.Request) {
+		permissions.ListPermissionsHandler(w, r, s)
+	})
+	admin.HandleFunc("PUT /api/permissions/update/{clientId}", func(w http.ResponseWriter, r *http.Request) {
+		permissions.UpdatePermissionHandler(w, r, s)
+	})
+	admin.HandleFunc("DELETE /api/permissions/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+		permissions.DeletePermissionHandler(w, r, s)
+	})
 
 	//mcp based services
-	router.HandleFunc("POST /api/mcp/register", mcp.RegisterClientHandler)
-	router.HandleFunc("GET /api/mcp/clients", mcp.ListClientsHandler)
-	router.HandleFunc("DELETE /api/mcp/clients/delete/{clientId}", mcp.DeleteClientHandler)
-	router.HandleFunc("PUT /api/mcp/clients/update/{clientId}", mcp.UpdateClientHandler)
+	admin.HandleFunc("POST /api/mcp/register", func(w http.ResponseWriter, r *http.Request) {
+		mcp.RegisterClientHandler(w, r, s)
+	})
+	admin.HandleFunc("GET /api/mcp/clients", func(w http.ResponseWriter, r *http.Request) {
+		mcp.ListClientsHandler(w, r, s)
+	})
+	admin.HandleFunc("DELETE /api/mcp/clients/delete/{clientId}", func(w http.ResponseWriter, r *http.Request) {
+		mcp.DeleteClientHandler(w, r, s)
+	})
+	admin.HandleFunc("PUT /api/mcp/clients/update/{clientId}", func(w http.ResponseWriter, r *http.Request) {
+		mcp.UpdateClientHandler(w, r, s, limiter)
+	})
+	admin.HandleFunc("GET /api/mcp/sessions", func(w http.ResponseWriter, r *http.Request) {
+		mcp.ListSessionsHandler(w, r, sessions)
+	})
+	admin.HandleFunc("DELETE /api/mcp/sessions/terminate/{sessionId}", func(w http.ResponseWriter, r *http.Request) {
+		mcp.TerminateSessionHandler(w, r, s, sessions)
+	})
+
+	switch {
+	case adminKey != "":
+		router.Handle("/api/", auth.AdminMiddleware(adminKey, admin))
+	case transports.Streamable != nil || transports.SSE != nil:
+		// agents reaching the MCP endpoints must not be able to register clients or grant themselves permissions
+		router.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "The REST API needs http_server.admin_key when MCP is served over HTTP", http.StatusForbidden)
+		})
+	default:
+		router.Handle("/api/", admin)
+	}
 
 	//activity service
 
@@ -4,47 +4,89 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/embeddings"
+	"github.com/aaryansinhaa/panes/utils/mcp"
 	"github.com/aaryansinhaa/panes/utils/server/api"
 	"github.com/aaryansinhaa/panes/utils/services/storage"
 	"github.com/aaryansinhaa/panes/utils/types"
 )
 
-func LoadServer(cfg *config.Config) {
-	fmt.Println("Please enter the port number to run the server on (default is 8080):")
+// defaultPort is used when no port is configured and none can be asked for
+const defaultPort = "8080"
+
+// Port returns the configured HTTP port. Without one it asks on stdin,
+// unless stdin carries the stdio MCP transport, and falls back to defaultPort
+func Port(cfg *config.Config) string {
+	if cfg.HTTPServer.Port != "" {
+		return cfg.HTTPServer.Port
+	}
+	if cfg.MCPServer.Enabled(config.TransportStdio) {
+		return defaultPort
+	}
+	// stdout is kept for the stdio MCP transport even when it is off, prompts go to stderr like the banner
+	fmt.Fprintln(os.Stderr, "Please enter the port number to run the server on (default is 8080):")
 	var port string
 	fmt.Scanln(&port)
 	if port == "" {
-		port = "8080"
+		port = defaultPort
 	}
+	return port
+}
+
+// LoadServer serves the REST API and the enabled HTTP based MCP transports on one HTTP server, sharing the given storage
+// and embedding index, which is nil without semantic search.
+// It blocks until ctx is cancelled, then shuts the server down within the configured shutdown timeout
+func LoadServer(ctx context.Context, cfg *config.Config, store *storage.SQLite, mcpServer *mcp.Server, index *embeddings.Index, port string) error {
+	slog.Info("Starting server", "port", port)
 
-	fmt.Printf("Starting server on port %s...\n", port)
+	// long-lived MCP streams (Streamable HTTP listeners and SSE sessions) are bound to this context so shutdown can end them
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 
-	// Connect to SQLite database
-	store, err := storage.LoadSQLiteStorage(cfg)
+	server := &http.Server{
+		Addr:        fmt.Sprintf("%s:%s", cfg.HTTPServer.Address, port),
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
+	transports := api.MCPTransports{}
+	if cfg.MCPServer.Enabled(config.TransportStreamableHTTP) {
+		transports.StreamablePath = cfg.MCPServer.EndpointPath
+		transports.Streamable = mcpServer.StreamableHTTPHandler(cfg.MCPServer.EndpointPath, cfg.MCPServer.SessionIdleTTL)
+	}
+	if cfg.MCPServer.Enabled(config.TransportSSE) {
+		transports.SSEPath = cfg.MCPServer.SSEEndpoint
+		transports.MessagePath = cfg.MCPServer.MessageEndpoint
+		transports.SSE, transports.Message = mcpServer.SSEHandlers(cfg.MCPServer.SSEEndpoint, cfg.MCPServer.MessageEndpoint)
+	}
+	adminKey, err := config.ExpandEnv(cfg.HTTPServer.AdminKey)
 	if err != nil {
-		fmt.Printf("Failed to connect to storage: %v\n", err)
-		return
+		return fmt.Errorf("http_server.admin_key: %w", err)
+	}
+	if adminKey == "" && (transports.Streamable != nil || transports.SSE != nil) {
+		slog.Warn("REST API disabled, set http_server.admin_key to manage clients while MCP is served over HTTP")
 	}
-	defer store.Close()
-	router := api.Router(store)
+	server.Handler = api.Router(store, adminKey, transports, mcpServer, mcpServer, mcpServer, index)
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf("%s:%s", cfg.HTTPServer.Address, port),
-		Handler: router,
+	// bind first so a port already in use is reported as a failure right away
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		store.CreateLogEntry(types.LogEntry{
+			Message:    fmt.Sprintf("Server error: %v", err),
+			Type:       "error",
+			Action:     "start",
+			ClientName: "admin",
+		})
+		return err
 	}
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 
+	serveErr := make(chan error, 1)
 	go func() {
-		fmt.Printf("MCP Server is running at http://%s:%s\n", cfg.HTTPServer.Address, port)
-		slog.Info("MCP Server started")
+		slog.Info("MCP Server started", "url", fmt.Sprintf("http://%s", listener.Addr()))
 		store.CreateLogEntry(types.LogEntry{
 			Message:    "MCP Server started",
 			Type:       "info",
@@ -52,27 +94,33 @@ func LoadServer(cfg *config.Config) {
 			ClientName: "admin",
 		},
 		)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("Server error", "error", err)
-			store.CreateLogEntry(types.LogEntry{
-				Message:    fmt.Sprintf("Server error: %v", err),
-				Type:       "error",
-				Action:     "start",
-				ClientName: "admin",
-			})
-		}
+		serveErr <- server.Serve(listener)
 	}()
 
-	// Block until shutdown signal
-	<-done
-	slog.Info("Shutdown signal received, shutting down...")
+	// Block until shutdown or a serving error
+	select {
+	case err := <-serveErr:
+		slog.Error("Server error", "error", err)
+		store.CreateLogEntry(types.LogEntry{
+			Message:    fmt.Sprintf("Server error: %v", err),
+			Type:       "error",
+			Action:     "start",
+			ClientName: "admin",
+		})
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("Shutting down HTTP server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	cancelBase()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		slog.Error("Error shutting down server", "error", err)
-	} else {
-		slog.Info("HTTP server shut down cleanly")
+		return err
 	}
+	slog.Info("HTTP server shut down cleanly")
+	return nil
 }
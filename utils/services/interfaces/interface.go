@@ -1,6 +1,10 @@
 package interfaces
 
-import "github.com/aaryansinhaa/panes/utils/types"
+import (
+	"context"
+
+	"github.com/aaryansinhaa/panes/utils/types"
+)
 
 type LogEntry interface {
 	CreateLogEntry(message, logType, action, clientName string) error
@@ -11,24 +15,82 @@ type LogEntry interface {
 
 type FileMetadata interface {
 	UploadFileMetadata(fileMetaData types.FileMetadata) error
-	ListFileMetadata() ([]types.FileMetadata, error)
+	ListFileMetadata(ctx context.Context) ([]types.FileMetadata, error)
+	ListFileMetadataPage(ctx context.Context, afterID int64, limit int) ([]types.FileMetadata, error)
+	GetFileMetadataByName(ctx context.Context, filename string) (types.FileMetadata, error)
+	UpdateFileMetadata(fileMetaData types.FileMetadata) error
 	DeleteFileMetadata(filename string) error
-	SearchFilesByName(pattern string, limit int) ([]types.FileMetadata, error)
+	ListReadableFiles(ctx context.Context, clientID string, offset, limit int) ([]types.FileMetadata, int, error)
+	SearchFilesByName(ctx context.Context, clientID, pattern string, limit int) ([]types.FileMetadata, error)
+	SaveRendition(ctx context.Context, rendition types.Rendition) error
+	GetRendition(ctx context.Context, fileID int64) (types.Rendition, error)
+	DeleteRendition(fileID int64) error
+	IndexFileContent(ctx context.Context, fileID int64, filename, content string) error
+	SearchFileContent(ctx context.Context, clientID, query string, limit int) ([]types.ContentMatch, error)
+	GetFileText(ctx context.Context, file types.FileMetadata) (string, error)
+}
+
+type Embedding interface {
+	SaveEmbeddings(fileID int64, model string, chunks []types.EmbeddedChunk) error
+	ListFilesWithoutEmbeddings(ctx context.Context, model string) ([]types.FileMetadata, error)
+	SearchEmbeddings(ctx context.Context, clientID, model string, query []float32, limit int) ([]types.SemanticMatch, error)
+}
+
+type Prompt interface {
+	CreatePrompt(prompt types.Prompt) error
+	ListPrompts() ([]types.Prompt, error)
+	GetPromptByName(name string) (types.Prompt, error)
+	UpdatePrompt(prompt types.Prompt) error
+	DeletePrompt(name string) error
+}
+
+// ResourceNotifier is told about changes to the files table so connected MCP sessions can be notified
+type ResourceNotifier interface {
+	FilesChanged()
+	FileUpdated(filename string)
+}
+
+// PromptNotifier is told about changes to the prompts table so connected MCP sessions can be notified
+type PromptNotifier interface {
+	PromptsChanged()
+}
+
+// Notifier is told about every change MCP sessions are notified of
+type Notifier interface {
+	ResourceNotifier
+	PromptNotifier
+}
+
+// RateLimiter throttles the MCP calls of clients
+type RateLimiter interface {
+	ResetRateLimits(clientID string)
+}
+
+// SessionRegistry lists the connected MCP sessions and can end them
+type SessionRegistry interface {
+	Sessions() []types.MCPSession
+	TerminateSession(sessionID string) error
 }
 
 type Client interface {
 	CreateClient(clientID, clientName, clientAPIHash string) error
-	GetClients(limit int) ([]Client, error)
-	GetClientByID(clientID string) (Client, error)
+	GetClients(limit int) ([]types.Client, error)
+	GetClientByID(clientID string) (types.Client, error)
+	GetClientByAPIHash(clientAPIHash string) (types.Client, error)
 	UpdateClient(clientID string, clientName string, clientAPIHash string) error
+	SetClientActive(clientID string, active bool) error
 	DeleteClient(clientID string) error
+	SetClientLimits(clientID string, limits types.ClientLimits, toolLimits map[string]types.ClientLimits) error
+	CountClientCall(clientID, day, scope string, quota int64) (int64, error)
+	GetClientUsage(clientID, day string) (map[string]int64, error)
 }
 
 type Permission interface {
 	CreatePermission(clientID, resource, action, resourceID string) error
-	GetPermissionsByClientID(clientID string) ([]Permission, error)
-	GetPermissionByID(id int64) (Permission, error)
+	GetPermissionsByClientID(clientID string) ([]types.Permission, error)
+	GetPermissionByID(id int64) (types.Permission, error)
 	DeletePermission(id int64) error
 	UpdatePermissionByClientID(clientID, resource, action, resourceID string) error
-	CheckPermission(clientID, resource, action string) (bool, error)
+	CheckPermission(ctx context.Context, clientID, resource, action string) (bool, error)
+	CheckResourcePermission(ctx context.Context, clientID, resource, action, resourceID string) (bool, error)
 }
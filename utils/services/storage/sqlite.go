@@ -1,44 +1,81 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/extract"
+	"github.com/aaryansinhaa/panes/utils/fileio"
 	"github.com/aaryansinhaa/panes/utils/types"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type SQLite struct {
 	DB *sql.DB
+	// contentSearch is set when SQLite was built with FTS5 and file contents are indexed
+	contentSearch bool
 }
 
+// ErrContentSearchUnavailable is returned by content searches when SQLite was built without FTS5
+var ErrContentSearchUnavailable = errors.New("content search needs SQLite with FTS5, build Panes with -tags sqlite_fts5")
+
 // Loads the essential SQLite connection and makes essential table for the application: Clients, Files, Permissions, and Logs
 func LoadSQLiteStorage(cfg *config.Config) (*SQLite, error) {
 	storage, err := sql.Open("sqlite3", cfg.StoragePath)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Printf("Connecting to SQLite database at %s\n", cfg.StoragePath)
+	slog.Info("Connecting to SQLite database", "path", cfg.StoragePath)
 	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS logs (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		message TEXT NOT NULL,
 		type TEXT NOT NULL,
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
 		action TEXT NOT NULL,
-		client_name TEXT NOT NULL
+		client_name TEXT NOT NULL,
+		target TEXT NOT NULL DEFAULT '', -- tool, resource URI or prompt an MCP request was about
+		duration_ms INTEGER NOT NULL DEFAULT 0
 	)`)
 	if err != nil {
 		return nil, err
 	}
+	if err = migrateLogs(storage); err != nil {
+		return nil, err
+	}
 	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS clients (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     client_id TEXT NOT NULL UNIQUE,
     client_name TEXT NOT NULL,
     client_api_hash TEXT NOT NULL,  -- encrypted/hashed API key
     active BOOLEAN DEFAULT TRUE,
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    rate_limit REAL NOT NULL DEFAULT 0, -- MCP calls per minute, 0 is unlimited
+    burst INTEGER NOT NULL DEFAULT 0,
+    daily_quota INTEGER NOT NULL DEFAULT 0, -- MCP calls per UTC day, 0 is unlimited
+    tool_limits TEXT NOT NULL DEFAULT '{}' -- JSON encoded limits replacing the above for single tools
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	if err = migrateClients(storage); err != nil {
+		return nil, err
+	}
+	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS client_usage (
+    client_id TEXT NOT NULL,
+    day TEXT NOT NULL, -- UTC, YYYY-MM-DD
+    scope TEXT NOT NULL, -- empty for the client quota, the tool name for tools with their own limits
+    calls INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (client_id, day, scope)
 	)`)
 	if err != nil {
 		return nil, err
@@ -58,6 +95,39 @@ func LoadSQLiteStorage(cfg *config.Config) (*SQLite, error) {
 		return nil, err
 	}
 
+	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS renditions (
+    file_id INTEGER PRIMARY KEY,
+    mime_type TEXT NOT NULL, -- text/markdown or text/plain
+    content TEXT NOT NULL,
+    extracted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (file_id) REFERENCES files(id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	contentSearch, err := createFileContents(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS embeddings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    file_id INTEGER NOT NULL,
+    model TEXT NOT NULL,
+    chunk_index INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    vector BLOB NOT NULL, -- little-endian float32 values
+    FOREIGN KEY (file_id) REFERENCES files(id)
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = storage.Exec("CREATE INDEX IF NOT EXISTS embeddings_file_model ON embeddings (file_id, model)")
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS permissions (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     client_id TEXT NOT NULL,
@@ -65,14 +135,159 @@ func LoadSQLiteStorage(cfg *config.Config) (*SQLite, error) {
     permission_type TEXT NOT NULL, -- READ, WRITE, DELETE
     allowed BOOLEAN DEFAULT FALSE,
     granted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    resource TEXT NOT NULL DEFAULT 'file', -- file, tool, ...
+    resource_id TEXT, -- NULL means every resource of that kind
     FOREIGN KEY (client_id) REFERENCES clients(client_id),
     FOREIGN KEY (file_id) REFERENCES files(id)
 	)`)
 	if err != nil {
 		return nil, err
 	}
+	if err = migratePermissions(storage); err != nil {
+		return nil, err
+	}
+
+	_, err = storage.Exec(`CREATE TABLE IF NOT EXISTS prompts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    template TEXT NOT NULL,
+    arguments TEXT NOT NULL DEFAULT '[]', -- JSON encoded list of prompt arguments
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLite{DB: storage, contentSearch: contentSearch}, nil
+}
+
+// createFileContents creates the FTS5 index over the text of the files, reporting whether SQLite supports it.
+// An index created by this call is filled from the files already uploaded
+func createFileContents(db *sql.DB) (bool, error) {
+	var fts5, exists bool
+	row := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_module_list WHERE name = 'fts5'),
+	EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'file_contents')`)
+	if err := row.Scan(&fts5, &exists); err != nil {
+		return false, err
+	}
+	if !fts5 {
+		slog.Warn("SQLite was built without FTS5, content search is disabled", "hint", "build Panes with -tags sqlite_fts5")
+		return false, nil
+	}
+	// rowid is the id of the file in the files table
+	_, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS file_contents USING fts5(
+    filename,
+    content,
+    tokenize = 'porter unicode61'
+	)`)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return true, nil
+	}
+	return true, indexExistingFiles(db)
+}
+
+// indexExistingFiles indexes the rendition of every document and the content of every text file
+func indexExistingFiles(db *sql.DB) error {
+	rows, err := db.Query(`SELECT f.id, f.filename, f.file_path, f.mime_type, r.content FROM files f
+	LEFT JOIN renditions r ON r.file_id = f.id`)
+	if err != nil {
+		return err
+	}
+	type indexed struct {
+		id                int64
+		filename, content string
+	}
+	var files []indexed
+	for rows.Next() {
+		var file indexed
+		var path string
+		var mimeType, rendition sql.NullString
+		if err := rows.Scan(&file.id, &file.filename, &path, &mimeType, &rendition); err != nil {
+			rows.Close()
+			return err
+		}
+		switch {
+		case rendition.Valid:
+			file.content = rendition.String
+		case extract.IsText(mimeType.String):
+			data, err := os.ReadFile(path)
+			if err != nil {
+				slog.Warn("Could not index file content", "filename", file.filename, "error", err)
+				continue
+			}
+			file.content = string(data)
+		default:
+			continue
+		}
+		files = append(files, file)
+	}
+	rows.Close()
 
-	return &SQLite{DB: storage}, nil
+	for _, file := range files {
+		if _, err := db.Exec("INSERT INTO file_contents (rowid, filename, content) VALUES (?, ?, ?)", file.id, file.filename, file.content); err != nil {
+			return err
+		}
+	}
+	slog.Info("Indexed file contents for search", "files", len(files))
+	return nil
+}
+
+// addColumn adds a column to a table created by an older version of Panes, reporting whether it was missing
+func addColumn(db *sql.DB, table, column, definition string) (bool, error) {
+	var exists bool
+	row := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column)
+	if err := row.Scan(&exists); err != nil || exists {
+		return false, err
+	}
+	if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return false, err
+	}
+	slog.Info("Migrated SQLite table", "table", table, "column", column)
+	return true, nil
+}
+
+// migratePermissions adds the resource columns to permissions tables created before grants could target more than files
+func migratePermissions(db *sql.DB) error {
+	if _, err := addColumn(db, "permissions", "resource", "TEXT NOT NULL DEFAULT 'file'"); err != nil {
+		return err
+	}
+	added, err := addColumn(db, "permissions", "resource_id", "TEXT")
+	if err != nil || !added {
+		return err
+	}
+	_, err = db.Exec("UPDATE permissions SET resource_id = CAST(file_id AS TEXT) WHERE file_id IS NOT NULL")
+	return err
+}
+
+// migrateLogs adds the audit columns to logs tables created before MCP requests were audited
+func migrateLogs(db *sql.DB) error {
+	if _, err := addColumn(db, "logs", "target", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	_, err := addColumn(db, "logs", "duration_ms", "INTEGER NOT NULL DEFAULT 0")
+	return err
+}
+
+// migrateClients adds the rate limit and quota columns to clients tables created before MCP calls were throttled
+func migrateClients(db *sql.DB) error {
+	columns := []struct{ name, definition string }{
+		{"rate_limit", "REAL NOT NULL DEFAULT 0"},
+		{"burst", "INTEGER NOT NULL DEFAULT 0"},
+		{"daily_quota", "INTEGER NOT NULL DEFAULT 0"},
+		{"tool_limits", "TEXT NOT NULL DEFAULT '{}'"},
+	}
+	for _, column := range columns {
+		if _, err := addColumn(db, "clients", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // close the sqlite connection
@@ -105,8 +320,8 @@ func (s *SQLite) UploadFileMetadata(fileMetaData types.FileMetadata) error {
 }
 
 // ListFiles lists all uploaded files from the SQLite database
-func (s *SQLite) ListFileMetadata() ([]types.FileMetadata, error) {
-	rows, err := s.DB.Query("SELECT id, filename, original_name, file_path, mime_type, file_size, uploaded_at, owner FROM files")
+func (s *SQLite) ListFileMetadata(ctx context.Context) ([]types.FileMetadata, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT id, filename, original_name, file_path, mime_type, file_size, uploaded_at, owner FROM files")
 	if err != nil {
 		slog.Error("Failed to list files", "error", err)
 		return nil, err
@@ -125,10 +340,72 @@ func (s *SQLite) ListFileMetadata() ([]types.FileMetadata, error) {
 	return files, nil
 }
 
-// SearchFilesByName searches for files by name pattern in the SQLite database
-func (s *SQLite) SearchFilesByName(pattern string, limit int) ([]types.FileMetadata, error) {
-	query := "SELECT id, filename, original_name, file_path, mime_type, file_size, uploaded_at, owner FROM files WHERE filename LIKE ? LIMIT ?"
-	rows, err := s.DB.Query(query, "%"+pattern+"%", limit)
+// granted is the permission rule as an SQL expression: the client, bound as the first parameter, holds the action on
+// the resource kind either globally or on resourceID. resource, action and resourceID are SQL expressions
+func granted(resource, action, resourceID string) string {
+	return `EXISTS (SELECT 1 FROM permissions p WHERE p.client_id = ? AND p.resource = ` + resource + `
+	AND LOWER(p.permission_type) = ` + action + ` AND p.allowed AND (p.resource_id IS NULL OR p.resource_id = ` + resourceID + `))`
+}
+
+// readableBy restricts a query on the files table, aliased f, to the files a client was granted read on, one by one or
+// all at once. It takes the client ID twice, an empty client ID is the local stdio client, which reads every file
+var readableBy = `(? = '' OR ` + granted("'file'", "'read'", "CAST(f.id AS TEXT)") + `)`
+
+// ListReadableFiles lists up to limit of the files a client may read, in ID order after skipping offset of them,
+// together with how many it may read in all
+func (s *SQLite) ListReadableFiles(ctx context.Context, clientID string, offset, limit int) ([]types.FileMetadata, int, error) {
+	var total int
+	row := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM files f WHERE "+readableBy, clientID, clientID)
+	if err := row.Scan(&total); err != nil {
+		slog.Error("Failed to count readable files", "client_id", clientID, "error", err)
+		return nil, 0, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT f.id, f.filename, f.original_name, f.file_path, f.mime_type, f.file_size, f.uploaded_at, f.owner
+	FROM files f WHERE `+readableBy+` ORDER BY f.id LIMIT ? OFFSET ?`, clientID, clientID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list readable files", "client_id", clientID, "error", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	files := []types.FileMetadata{}
+	for rows.Next() {
+		var file types.FileMetadata
+		if err := rows.Scan(&file.ID, &file.Filename, &file.OriginalName, &file.FilePath, &file.MimeType, &file.FileSize, &file.UploadedAt, &file.Owner); err != nil {
+			slog.Error("Failed to scan file row", "error", err)
+			return nil, 0, err
+		}
+		files = append(files, file)
+	}
+	return files, total, rows.Err()
+}
+
+// ListFileMetadataPage lists up to limit files whose ID comes after afterID, in ID order
+func (s *SQLite) ListFileMetadataPage(ctx context.Context, afterID int64, limit int) ([]types.FileMetadata, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT id, filename, original_name, file_path, mime_type, file_size, uploaded_at, owner FROM files WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		slog.Error("Failed to list files", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []types.FileMetadata
+	for rows.Next() {
+		var file types.FileMetadata
+		if err := rows.Scan(&file.ID, &file.Filename, &file.OriginalName, &file.FilePath, &file.MimeType, &file.FileSize, &file.UploadedAt, &file.Owner); err != nil {
+			slog.Error("Failed to scan file row", "error", err)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// SearchFilesByName searches for files by name pattern among the files a client may read, every file for an empty
+// client ID, in the SQLite database
+func (s *SQLite) SearchFilesByName(ctx context.Context, clientID, pattern string, limit int) ([]types.FileMetadata, error) {
+	query := "SELECT f.id, f.filename, f.original_name, f.file_path, f.mime_type, f.file_size, f.uploaded_at, f.owner FROM files f WHERE f.filename LIKE ? AND " + readableBy + " LIMIT ?"
+	rows, err := s.DB.QueryContext(ctx, query, "%"+pattern+"%", clientID, clientID, limit)
 	if err != nil {
 		slog.Error("Failed to search files by name", "error", err)
 		return nil, err
@@ -147,8 +424,52 @@ func (s *SQLite) SearchFilesByName(pattern string, limit int) ([]types.FileMetad
 	return files, nil
 }
 
-// DeleteFileMetadata deletes file metadata by file name(which are bound to be unique), from the SQLite database
+// GetFileMetadataByName retrieves the metadata of a single file by its exact filename from the SQLite database
+func (s *SQLite) GetFileMetadataByName(ctx context.Context, filename string) (types.FileMetadata, error) {
+	var file types.FileMetadata
+	row := s.DB.QueryRowContext(ctx, "SELECT id, filename, original_name, file_path, mime_type, file_size, uploaded_at, owner FROM files WHERE filename = ?", filename)
+	if err := row.Scan(&file.ID, &file.Filename, &file.OriginalName, &file.FilePath, &file.MimeType, &file.FileSize, &file.UploadedAt, &file.Owner); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("Failed to get file metadata", "filename", filename, "error", err)
+		}
+		return file, err
+	}
+	return file, nil
+}
+
+// UpdateFileMetadata replaces the metadata of an already uploaded file, matched by filename, in the SQLite database
+func (s *SQLite) UpdateFileMetadata(fileMetaData types.FileMetadata) error {
+	result, err := s.DB.Prepare(`UPDATE files SET original_name = ?, file_path = ?, mime_type = ?, owner = ?, file_size = ?, uploaded_at = CURRENT_TIMESTAMP
+	WHERE filename = ?`)
+	if err != nil {
+		slog.Error("Failed to prepare file metadata update", "error", err)
+		return err
+	}
+	_, err = result.Exec(fileMetaData.OriginalName, fileMetaData.FilePath, fileMetaData.MimeType, fileMetaData.Owner, fileMetaData.FileSize, fileMetaData.Filename)
+	if err != nil {
+		slog.Error("Failed to execute file metadata update", "error", err)
+		return err
+	}
+	slog.Info("File metadata updated successfully", "filename", fileMetaData.Filename)
+	return nil
+}
+
+// DeleteFileMetadata deletes file metadata by file name(which are bound to be unique), and its rendition, from the SQLite database
 func (s *SQLite) DeleteFileMetadata(filename string) error {
+	if _, err := s.DB.Exec("DELETE FROM renditions WHERE file_id IN (SELECT id FROM files WHERE filename = ?)", filename); err != nil {
+		slog.Error("Failed to delete file rendition", "error", err)
+		return err
+	}
+	if _, err := s.DB.Exec("DELETE FROM embeddings WHERE file_id IN (SELECT id FROM files WHERE filename = ?)", filename); err != nil {
+		slog.Error("Failed to delete file embeddings", "error", err)
+		return err
+	}
+	if s.contentSearch {
+		if _, err := s.DB.Exec("DELETE FROM file_contents WHERE rowid IN (SELECT id FROM files WHERE filename = ?)", filename); err != nil {
+			slog.Error("Failed to delete file content index", "error", err)
+			return err
+		}
+	}
 	result, err := s.DB.Prepare("DELETE FROM files WHERE filename = ?")
 	if err != nil {
 		slog.Error("Failed to prepare delete statement", "error", err)
@@ -163,19 +484,758 @@ func (s *SQLite) DeleteFileMetadata(filename string) error {
 	return nil
 }
 
+// SaveRendition stores the text extracted from a file, replacing the previous one, in the SQLite database
+func (s *SQLite) SaveRendition(ctx context.Context, rendition types.Rendition) error {
+	result, err := s.DB.PrepareContext(ctx, `INSERT INTO renditions (file_id, mime_type, content) VALUES (?, ?, ?)
+	ON CONFLICT (file_id) DO UPDATE SET mime_type = excluded.mime_type, content = excluded.content, extracted_at = CURRENT_TIMESTAMP`)
+	if err != nil {
+		slog.Error("Failed to prepare rendition save", "error", err)
+		return err
+	}
+	_, err = result.ExecContext(ctx, rendition.FileID, rendition.MimeType, rendition.Content)
+	if err != nil {
+		slog.Error("Failed to execute rendition save", "error", err)
+		return err
+	}
+	slog.Info("File rendition saved successfully", "file_id", rendition.FileID, "mime_type", rendition.MimeType)
+	return nil
+}
+
+// GetRendition retrieves the text extracted from a file from the SQLite database, sql.ErrNoRows when it has none
+func (s *SQLite) GetRendition(ctx context.Context, fileID int64) (types.Rendition, error) {
+	var rendition types.Rendition
+	row := s.DB.QueryRowContext(ctx, "SELECT file_id, mime_type, content, extracted_at FROM renditions WHERE file_id = ?", fileID)
+	err := row.Scan(&rendition.FileID, &rendition.MimeType, &rendition.Content, &rendition.ExtractedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Failed to get file rendition", "file_id", fileID, "error", err)
+	}
+	return rendition, err
+}
+
+// DeleteRendition deletes the text extracted from a file from the SQLite database
+func (s *SQLite) DeleteRendition(fileID int64) error {
+	if _, err := s.DB.Exec("DELETE FROM renditions WHERE file_id = ?", fileID); err != nil {
+		slog.Error("Failed to delete file rendition", "file_id", fileID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// IndexFileContent replaces the text of a file in the content search index, empty text removes the file from it
+func (s *SQLite) IndexFileContent(ctx context.Context, fileID int64, filename, content string) error {
+	if !s.contentSearch {
+		return nil
+	}
+	if _, err := s.DB.ExecContext(ctx, "DELETE FROM file_contents WHERE rowid = ?", fileID); err != nil {
+		slog.Error("Failed to delete file content index", "file_id", fileID, "error", err)
+		return err
+	}
+	if content == "" {
+		return nil
+	}
+	result, err := s.DB.PrepareContext(ctx, "INSERT INTO file_contents (rowid, filename, content) VALUES (?, ?, ?)")
+	if err != nil {
+		slog.Error("Failed to prepare file content index", "error", err)
+		return err
+	}
+	if _, err = result.ExecContext(ctx, fileID, filename, content); err != nil {
+		slog.Error("Failed to index file content", "file_id", fileID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ContentSearch reports whether file contents are indexed for SearchFileContent, which needs SQLite with FTS5
+func (s *SQLite) ContentSearch() bool {
+	return s.contentSearch
+}
+
+// SearchFileContent finds the files the client may read whose name or text match the query, best BM25 score first,
+// with a snippet of the matching text in which the matched terms are wrapped in **. An empty client ID searches every file
+func (s *SQLite) SearchFileContent(ctx context.Context, clientID, query string, limit int) ([]types.ContentMatch, error) {
+	if !s.contentSearch {
+		return nil, ErrContentSearchUnavailable
+	}
+	match := matchQuery(query)
+	if match == "" {
+		return []types.ContentMatch{}, nil
+	}
+	// bm25 is lower for better matches, filename matches weigh twice as much as content matches
+	rows, err := s.DB.QueryContext(ctx, `SELECT f.id, f.filename, f.original_name, f.file_path, f.mime_type, f.file_size, f.uploaded_at, f.owner,
+	snippet(file_contents, 1, '**', '**', '…', 16), -bm25(file_contents, 2.0, 1.0)
+	FROM file_contents JOIN files f ON f.id = file_contents.rowid
+	WHERE file_contents MATCH ? AND `+readableBy+` ORDER BY bm25(file_contents, 2.0, 1.0) LIMIT ?`, match, clientID, clientID, limit)
+	if err != nil {
+		slog.Error("Failed to search file contents", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []types.ContentMatch{}
+	for rows.Next() {
+		var m types.ContentMatch
+		file := &m.File
+		if err := rows.Scan(&file.ID, &file.Filename, &file.OriginalName, &file.FilePath, &file.MimeType, &file.FileSize, &file.UploadedAt, &file.Owner, &m.Snippet, &m.Score); err != nil {
+			slog.Error("Failed to scan content match", "error", err)
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// matchQuery turns free text into an FTS5 query matching every word, a trailing * keeps a word a prefix search
+func matchQuery(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Trim(word, "*")
+		if word == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+// GetFileText returns the text of a file: the rendition of a document, the content of a text file, and nothing for
+// other files
+func (s *SQLite) GetFileText(ctx context.Context, file types.FileMetadata) (string, error) {
+	rendition, err := s.GetRendition(ctx, file.ID)
+	if err == nil {
+		return rendition.Content, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) || !extract.IsText(file.MimeType) {
+		return "", err
+	}
+	data, err := fileio.ReadAll(ctx, file.FilePath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 //--------------------------------FILE RELATED SERVICES END-------------------------------------
 
+//-------------------------------------EMBEDDING RELATED SERVICES-------------------------------------
+
+// SaveEmbeddings replaces the embedded chunks of a file for a model in the SQLite database, nothing is saved for a
+// file that no longer exists
+func (s *SQLite) SaveEmbeddings(fileID int64, model string, chunks []types.EmbeddedChunk) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin embeddings save", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// the file may have been deleted while its text was being embedded
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM files WHERE id = ?)", fileID).Scan(&exists); err != nil {
+		slog.Error("Failed to check file of embeddings", "file_id", fileID, "error", err)
+		return err
+	}
+	if !exists {
+		slog.Info("File deleted before its embeddings were saved", "file_id", fileID)
+		return nil
+	}
+	if _, err := tx.Exec("DELETE FROM embeddings WHERE file_id = ? AND model = ?", fileID, model); err != nil {
+		slog.Error("Failed to delete previous embeddings", "file_id", fileID, "error", err)
+		return err
+	}
+	insert, err := tx.Prepare("INSERT INTO embeddings (file_id, model, chunk_index, content, vector) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		slog.Error("Failed to prepare embeddings save", "error", err)
+		return err
+	}
+	defer insert.Close()
+	for _, chunk := range chunks {
+		if _, err := insert.Exec(fileID, model, chunk.Index, chunk.Content, encodeVector(chunk.Vector)); err != nil {
+			slog.Error("Failed to execute embeddings save", "file_id", fileID, "error", err)
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Failed to commit embeddings save", "error", err)
+		return err
+	}
+	slog.Info("File embeddings saved successfully", "file_id", fileID, "chunks", len(chunks))
+	return nil
+}
+
+// ListFilesWithoutEmbeddings lists the files that have no embedded chunks for a model in the SQLite database
+func (s *SQLite) ListFilesWithoutEmbeddings(ctx context.Context, model string) ([]types.FileMetadata, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, filename, original_name, file_path, mime_type, file_size, uploaded_at, owner FROM files
+	WHERE NOT EXISTS (SELECT 1 FROM embeddings WHERE file_id = files.id AND model = ?) ORDER BY id`, model)
+	if err != nil {
+		slog.Error("Failed to list files without embeddings", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []types.FileMetadata
+	for rows.Next() {
+		var file types.FileMetadata
+		if err := rows.Scan(&file.ID, &file.Filename, &file.OriginalName, &file.FilePath, &file.MimeType, &file.FileSize, &file.UploadedAt, &file.Owner); err != nil {
+			slog.Error("Failed to scan file row", "error", err)
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// SearchEmbeddings finds the files the client may read with the chunks most similar to the query vector by cosine
+// similarity, one match per file with its best chunk, best first. An empty client ID searches every file
+func (s *SQLite) SearchEmbeddings(ctx context.Context, clientID, model string, query []float32, limit int) ([]types.SemanticMatch, error) {
+	// chunks come grouped by file with the file's row, a file deleted meanwhile is simply not joined
+	rows, err := s.DB.QueryContext(ctx, `SELECT e.content, e.vector, f.id, f.filename, f.original_name, f.file_path, f.mime_type, f.file_size, f.uploaded_at, f.owner
+	FROM embeddings e JOIN files f ON f.id = e.file_id WHERE e.model = ? AND `+readableBy+` ORDER BY e.file_id`, model, clientID, clientID)
+	if err != nil {
+		slog.Error("Failed to search embeddings", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// only the best limit files are kept, each with its best chunk
+	top := []types.SemanticMatch{}
+	var best types.SemanticMatch
+	for rows.Next() {
+		var match types.SemanticMatch
+		var vector []byte
+		file := &match.File
+		if err := rows.Scan(&match.Chunk, &vector, &file.ID, &file.Filename, &file.OriginalName, &file.FilePath, &file.MimeType, &file.FileSize, &file.UploadedAt, &file.Owner); err != nil {
+			slog.Error("Failed to scan embedding row", "error", err)
+			return nil, err
+		}
+		match.Score = cosine(query, decodeVector(vector))
+		if best.File.ID == file.ID {
+			if match.Score > best.Score {
+				best = match
+			}
+			continue
+		}
+		top = keepBest(top, best, limit)
+		best = match
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keepBest(top, best, limit), nil
+}
+
+// keepBest inserts the match of a file into the best first list of at most limit matches, if it ranks
+func keepBest(top []types.SemanticMatch, match types.SemanticMatch, limit int) []types.SemanticMatch {
+	if match.File.ID == 0 {
+		return top
+	}
+	i := sort.Search(len(top), func(i int) bool { return top[i].Score < match.Score })
+	if i >= limit {
+		return top
+	}
+	top = slices.Insert(top, i, match)
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// encodeVector stores a vector as little-endian float32 values
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
+
+// cosine is the cosine similarity of two vectors, 0 when their lengths differ or one of them is zero
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+//-----------------------------EMBEDDING RELATED SERVICES END-------------------------------------
+
+//-------------------------------------CLIENT RELATED SERVICES-------------------------------------
+
+// CreateClient registers a new MCP client with the hash of its API key in the SQLite database
+func (s *SQLite) CreateClient(clientID, clientName, clientAPIHash string) error {
+	result, err := s.DB.Prepare(`INSERT INTO clients (client_id, client_name, client_api_hash) VALUES (?, ?, ?)`)
+	if err != nil {
+		slog.Error("Failed to prepare client insert", "error", err)
+		return err
+	}
+	_, err = result.Exec(clientID, clientName, clientAPIHash)
+	if err != nil {
+		slog.Error("Failed to execute client insert", "error", err)
+		return err
+	}
+	slog.Info("Client created successfully", "client_id", clientID)
+	return nil
+}
+
+// GetClients lists the registered MCP clients, newest first, from the SQLite database
+func (s *SQLite) GetClients(limit int) ([]types.Client, error) {
+	rows, err := s.DB.Query("SELECT "+clientColumns+" FROM clients ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		slog.Error("Failed to list clients", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []types.Client
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			slog.Error("Failed to scan client row", "error", err)
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// GetClientByID retrieves a single MCP client by its client ID from the SQLite database
+func (s *SQLite) GetClientByID(clientID string) (types.Client, error) {
+	return s.getClient("client_id", clientID)
+}
+
+// GetClientByAPIHash retrieves the MCP client owning an API key hash from the SQLite database
+func (s *SQLite) GetClientByAPIHash(clientAPIHash string) (types.Client, error) {
+	return s.getClient("client_api_hash", clientAPIHash)
+}
+
+func (s *SQLite) getClient(column, value string) (types.Client, error) {
+	row := s.DB.QueryRow("SELECT "+clientColumns+" FROM clients WHERE "+column+" = ?", value)
+	client, err := scanClient(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Failed to get client", "by", column, "error", err)
+	}
+	return client, err
+}
+
+// clientColumns are the columns scanClient reads
+const clientColumns = "id, client_id, client_name, client_api_hash, active, created_at, rate_limit, burst, daily_quota, tool_limits"
+
+// scanClient reads a clients row, decoding its JSON encoded tool limits
+func scanClient(row interface{ Scan(dest ...any) error }) (types.Client, error) {
+	var client types.Client
+	var toolLimits string
+	err := row.Scan(&client.ID, &client.ClientID, &client.ClientName, &client.ClientAPIHash, &client.Active, &client.CreatedAt,
+		&client.RateLimit, &client.Burst, &client.DailyQuota, &toolLimits)
+	if err != nil {
+		return client, err
+	}
+	if err := json.Unmarshal([]byte(toolLimits), &client.ToolLimits); err != nil {
+		return client, err
+	}
+	return client, nil
+}
+
+// SetClientLimits replaces the rate limits and daily quotas of a client in the SQLite database
+func (s *SQLite) SetClientLimits(clientID string, limits types.ClientLimits, toolLimits map[string]types.ClientLimits) error {
+	if toolLimits == nil {
+		toolLimits = map[string]types.ClientLimits{}
+	}
+	encoded, err := json.Marshal(toolLimits)
+	if err != nil {
+		slog.Error("Failed to encode tool limits", "error", err)
+		return err
+	}
+	res, err := s.DB.Exec("UPDATE clients SET rate_limit = ?, burst = ?, daily_quota = ?, tool_limits = ? WHERE client_id = ?",
+		limits.RateLimit, limits.Burst, limits.DailyQuota, string(encoded), clientID)
+	if err != nil {
+		slog.Error("Failed to update client limits", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Client limits updated successfully", "client_id", clientID)
+	return nil
+}
+
+// CountClientCall adds a call to the daily usage of a client under scope and returns the calls of that day so far.
+// With a positive quota, calls past it are not counted and sql.ErrNoRows is returned instead
+func (s *SQLite) CountClientCall(clientID, day, scope string, quota int64) (int64, error) {
+	var calls int64
+	err := s.DB.QueryRow(`INSERT INTO client_usage (client_id, day, scope, calls) VALUES (?, ?, ?, 1)
+	ON CONFLICT (client_id, day, scope) DO UPDATE SET calls = calls + 1 WHERE ? <= 0 OR calls < ? RETURNING calls`,
+		clientID, day, scope, quota, quota).Scan(&calls)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Failed to count client call", "client_id", clientID, "error", err)
+	}
+	return calls, err
+}
+
+// GetClientUsage returns the calls of a client on a day by scope from the SQLite database
+func (s *SQLite) GetClientUsage(clientID, day string) (map[string]int64, error) {
+	rows, err := s.DB.Query("SELECT scope, calls FROM client_usage WHERE client_id = ? AND day = ?", clientID, day)
+	if err != nil {
+		slog.Error("Failed to get client usage", "client_id", clientID, "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int64)
+	for rows.Next() {
+		var scope string
+		var calls int64
+		if err := rows.Scan(&scope, &calls); err != nil {
+			slog.Error("Failed to scan client usage row", "error", err)
+			return nil, err
+		}
+		usage[scope] = calls
+	}
+	return usage, rows.Err()
+}
+
+// UpdateClient renames a client and, when clientAPIHash is not empty, replaces its API key hash in the SQLite database
+func (s *SQLite) UpdateClient(clientID string, clientName string, clientAPIHash string) error {
+	result, err := s.DB.Prepare(`UPDATE clients SET client_name = ?, client_api_hash = COALESCE(NULLIF(?, ''), client_api_hash) WHERE client_id = ?`)
+	if err != nil {
+		slog.Error("Failed to prepare client update", "error", err)
+		return err
+	}
+	res, err := result.Exec(clientName, clientAPIHash, clientID)
+	if err != nil {
+		slog.Error("Failed to execute client update", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Client updated successfully", "client_id", clientID)
+	return nil
+}
+
+// SetClientActive activates or deactivates a client in the SQLite database, deactivated clients are refused by the MCP transports
+func (s *SQLite) SetClientActive(clientID string, active bool) error {
+	res, err := s.DB.Exec("UPDATE clients SET active = ? WHERE client_id = ?", active, clientID)
+	if err != nil {
+		slog.Error("Failed to update client status", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Client status updated successfully", "client_id", clientID, "active", active)
+	return nil
+}
+
+// DeleteClient deletes a client by client ID from the SQLite database
+func (s *SQLite) DeleteClient(clientID string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin client delete", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+	// a client created again under the same ID starts without the grants and usage of this one
+	if _, err := tx.Exec("DELETE FROM permissions WHERE client_id = ?", clientID); err != nil {
+		slog.Error("Failed to delete client permissions", "error", err)
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM client_usage WHERE client_id = ?", clientID); err != nil {
+		slog.Error("Failed to delete client usage", "error", err)
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM clients WHERE client_id = ?", clientID)
+	if err != nil {
+		slog.Error("Failed to delete client", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Failed to commit client delete", "error", err)
+		return err
+	}
+	slog.Info("Client deleted successfully", "client_id", clientID)
+	return nil
+}
+
+//-----------------------------CLIENT RELATED SERVICES END-------------------------------------
+
+//-------------------------------------PERMISSION RELATED SERVICES-------------------------------------
+
+// CreatePermission grants a client an action on a resource, on every resource of that kind when resourceID is empty
+func (s *SQLite) CreatePermission(clientID, resource, action, resourceID string) error {
+	result, err := s.DB.Prepare(`INSERT INTO permissions (client_id, resource, permission_type, resource_id, file_id, allowed)
+	VALUES (?, ?, ?, NULLIF(?, ''), ?, TRUE)`)
+	if err != nil {
+		slog.Error("Failed to prepare permission insert", "error", err)
+		return err
+	}
+	_, err = result.Exec(clientID, resource, strings.ToLower(action), resourceID, permissionFileID(resource, resourceID))
+	if err != nil {
+		slog.Error("Failed to execute permission insert", "error", err)
+		return err
+	}
+	slog.Info("Permission granted successfully", "client_id", clientID, "resource", resource, "action", action, "resource_id", resourceID)
+	return nil
+}
+
+// GetPermissionsByClientID lists the permissions granted to a client from the SQLite database
+func (s *SQLite) GetPermissionsByClientID(clientID string) ([]types.Permission, error) {
+	rows, err := s.DB.Query(`SELECT id, client_id, resource, permission_type, COALESCE(resource_id, ''), granted_at FROM permissions
+	WHERE client_id = ? AND allowed ORDER BY id`, clientID)
+	if err != nil {
+		slog.Error("Failed to list permissions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []types.Permission
+	for rows.Next() {
+		var permission types.Permission
+		if err := rows.Scan(&permission.ID, &permission.ClientID, &permission.Resource, &permission.Action, &permission.ResourceID, &permission.CreatedAt); err != nil {
+			slog.Error("Failed to scan permission row", "error", err)
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, nil
+}
+
+// GetPermissionByID retrieves a single permission by ID from the SQLite database
+func (s *SQLite) GetPermissionByID(id int64) (types.Permission, error) {
+	var permission types.Permission
+	row := s.DB.QueryRow(`SELECT id, client_id, resource, permission_type, COALESCE(resource_id, ''), granted_at FROM permissions WHERE id = ?`, id)
+	err := row.Scan(&permission.ID, &permission.ClientID, &permission.Resource, &permission.Action, &permission.ResourceID, &permission.CreatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Failed to get permission", "id", id, "error", err)
+	}
+	return permission, err
+}
+
+// DeletePermission revokes a permission by ID in the SQLite database
+func (s *SQLite) DeletePermission(id int64) error {
+	res, err := s.DB.Exec("DELETE FROM permissions WHERE id = ?", id)
+	if err != nil {
+		slog.Error("Failed to delete permission", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Permission deleted successfully", "id", id)
+	return nil
+}
+
+// UpdatePermissionByClientID replaces every grant a client has on a resource kind with a single one
+func (s *SQLite) UpdatePermissionByClientID(clientID, resource, action, resourceID string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		slog.Error("Failed to begin permission update", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM permissions WHERE client_id = ? AND resource = ?", clientID, resource); err != nil {
+		slog.Error("Failed to clear permissions", "error", err)
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO permissions (client_id, resource, permission_type, resource_id, file_id, allowed)
+	VALUES (?, ?, ?, NULLIF(?, ''), ?, TRUE)`, clientID, resource, strings.ToLower(action), resourceID, permissionFileID(resource, resourceID))
+	if err != nil {
+		slog.Error("Failed to insert permission", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Failed to commit permission update", "error", err)
+		return err
+	}
+	slog.Info("Permissions updated successfully", "client_id", clientID, "resource", resource)
+	return nil
+}
+
+// CheckPermission reports whether a client holds a global grant of an action on a resource kind
+func (s *SQLite) CheckPermission(ctx context.Context, clientID, resource, action string) (bool, error) {
+	return s.CheckResourcePermission(ctx, clientID, resource, action, "")
+}
+
+// CheckResourcePermission reports whether a client may perform an action on one resource,
+// either through a grant on that resource ID or through a global grant on its kind
+func (s *SQLite) CheckResourcePermission(ctx context.Context, clientID, resource, action, resourceID string) (bool, error) {
+	var allowed bool
+	row := s.DB.QueryRowContext(ctx, "SELECT "+granted("?", "?", "NULLIF(?, '')"), clientID, resource, strings.ToLower(action), resourceID)
+	if err := row.Scan(&allowed); err != nil {
+		slog.Error("Failed to check permission", "client_id", clientID, "error", err)
+		return false, err
+	}
+	return allowed, nil
+}
+
+// permissionFileID keeps the legacy file_id column filled for grants on a single file
+func permissionFileID(resource, resourceID string) any {
+	if resource != "file" || resourceID == "" {
+		return nil
+	}
+	return resourceID
+}
+
+//-----------------------------PERMISSION RELATED SERVICES END-------------------------------------
+
+//-------------------------------------PROMPT RELATED SERVICES-------------------------------------
+
+// CreatePrompt stores a new named prompt template in the SQLite database
+func (s *SQLite) CreatePrompt(prompt types.Prompt) error {
+	arguments, err := json.Marshal(promptArguments(prompt.Arguments))
+	if err != nil {
+		slog.Error("Failed to encode prompt arguments", "error", err)
+		return err
+	}
+	result, err := s.DB.Prepare(`INSERT INTO prompts (name, description, template, arguments) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		slog.Error("Failed to prepare prompt insert", "error", err)
+		return err
+	}
+	_, err = result.Exec(prompt.Name, prompt.Description, prompt.Template, string(arguments))
+	if err != nil {
+		slog.Error("Failed to execute prompt insert", "error", err)
+		return err
+	}
+	slog.Info("Prompt created successfully", "name", prompt.Name)
+	return nil
+}
+
+// ListPrompts lists all stored prompt templates from the SQLite database
+func (s *SQLite) ListPrompts() ([]types.Prompt, error) {
+	rows, err := s.DB.Query("SELECT id, name, description, template, arguments, version, created_at, updated_at FROM prompts ORDER BY name")
+	if err != nil {
+		slog.Error("Failed to list prompts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prompts []types.Prompt
+	for rows.Next() {
+		prompt, err := scanPrompt(rows)
+		if err != nil {
+			slog.Error("Failed to scan prompt row", "error", err)
+			return nil, err
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, nil
+}
+
+// GetPromptByName retrieves a single prompt template by its unique name from the SQLite database
+func (s *SQLite) GetPromptByName(name string) (types.Prompt, error) {
+	row := s.DB.QueryRow("SELECT id, name, description, template, arguments, version, created_at, updated_at FROM prompts WHERE name = ?", name)
+	prompt, err := scanPrompt(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Failed to get prompt", "name", name, "error", err)
+	}
+	return prompt, err
+}
+
+// UpdatePrompt replaces a prompt template, matched by name, and bumps its version in the SQLite database
+func (s *SQLite) UpdatePrompt(prompt types.Prompt) error {
+	arguments, err := json.Marshal(promptArguments(prompt.Arguments))
+	if err != nil {
+		slog.Error("Failed to encode prompt arguments", "error", err)
+		return err
+	}
+	result, err := s.DB.Prepare(`UPDATE prompts SET description = ?, template = ?, arguments = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+	WHERE name = ?`)
+	if err != nil {
+		slog.Error("Failed to prepare prompt update", "error", err)
+		return err
+	}
+	res, err := result.Exec(prompt.Description, prompt.Template, string(arguments), prompt.Name)
+	if err != nil {
+		slog.Error("Failed to execute prompt update", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Prompt updated successfully", "name", prompt.Name)
+	return nil
+}
+
+// DeletePrompt deletes a prompt template by name from the SQLite database
+func (s *SQLite) DeletePrompt(name string) error {
+	result, err := s.DB.Prepare("DELETE FROM prompts WHERE name = ?")
+	if err != nil {
+		slog.Error("Failed to prepare prompt delete statement", "error", err)
+		return err
+	}
+	res, err := result.Exec(name)
+	if err != nil {
+		slog.Error("Failed to delete prompt", "error", err)
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Prompt deleted successfully", "name", name)
+	return nil
+}
+
+// promptArguments makes sure a prompt without arguments is stored as an empty list rather than null
+func promptArguments(arguments []types.PromptArgument) []types.PromptArgument {
+	if arguments == nil {
+		return []types.PromptArgument{}
+	}
+	return arguments
+}
+
+// scanPrompt reads a prompts row, decoding its JSON encoded arguments
+func scanPrompt(row interface{ Scan(dest ...any) error }) (types.Prompt, error) {
+	var prompt types.Prompt
+	var description sql.NullString
+	var arguments string
+	if err := row.Scan(&prompt.ID, &prompt.Name, &description, &prompt.Template, &arguments, &prompt.Version, &prompt.CreatedAt, &prompt.UpdatedAt); err != nil {
+		return prompt, err
+	}
+	prompt.Description = description.String
+	if err := json.Unmarshal([]byte(arguments), &prompt.Arguments); err != nil {
+		return prompt, err
+	}
+	return prompt, nil
+}
+
+//-----------------------------PROMPT RELATED SERVICES END-------------------------------------
+
 //-------------------------------------LOG RELATED SERVICES-------------------------------------
 
 // Create Log Entry creates a log entry in the SQLite database
 func (s *SQLite) CreateLogEntry(log types.LogEntry) error {
-	result, err := s.DB.Prepare(`INSERT INTO logs (message, type, action, client_name) VALUES (?, ?, ?, ?)`)
+	result, err := s.DB.Prepare(`INSERT INTO logs (message, type, action, client_name, target, duration_ms) VALUES (?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		slog.Error("Failed to prepare log entry statement", "error", err)
 		return err
 	}
 
-	_, err = result.Exec(log.Message, log.Type, log.Action, log.ClientName)
+	_, err = result.Exec(log.Message, log.Type, log.Action, log.ClientName, log.Target, log.DurationMs)
 	if err != nil {
 		slog.Error("Failed to execute log entry statement", "error", err)
 		return err
@@ -186,7 +1246,7 @@ func (s *SQLite) CreateLogEntry(log types.LogEntry) error {
 
 // Get Log Entries retrieves log entries from the SQLite database
 func (s *SQLite) GetLogEntries(limit int) ([]types.LogEntry, error) {
-	rows, err := s.DB.Query("SELECT id, message, type, timestamp, action, client_name FROM logs ORDER BY timestamp DESC LIMIT ?", limit)
+	rows, err := s.DB.Query("SELECT id, message, type, timestamp, action, client_name, target, duration_ms FROM logs ORDER BY timestamp DESC, id DESC LIMIT ?", limit)
 	if err != nil {
 		slog.Error("Failed to retrieve log entries", "error", err)
 		return nil, err
@@ -196,7 +1256,7 @@ func (s *SQLite) GetLogEntries(limit int) ([]types.LogEntry, error) {
 	var logs []types.LogEntry
 	for rows.Next() {
 		var log types.LogEntry
-		if err := rows.Scan(&log.ID, &log.Message, &log.Type, &log.Timestamp, &log.Action, &log.ClientName); err != nil {
+		if err := rows.Scan(&log.ID, &log.Message, &log.Type, &log.Timestamp, &log.Action, &log.ClientName, &log.Target, &log.DurationMs); err != nil {
 			slog.Error("Failed to scan log row", "error", err)
 			return nil, err
 		}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// newTestStorage opens a fresh database in a temporary directory
+func newTestStorage(t *testing.T) *SQLite {
+	t.Helper()
+	s, err := LoadSQLiteStorage(&config.Config{StoragePath: filepath.Join(t.TempDir(), "panes.db")})
+	if err != nil {
+		t.Fatalf("LoadSQLiteStorage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// addTestFile uploads the metadata of a file and returns its ID
+func addTestFile(t *testing.T, s *SQLite, filename string) int64 {
+	t.Helper()
+	err := s.UploadFileMetadata(types.FileMetadata{Filename: filename, OriginalName: filename, FilePath: "uploads/" + filename, MimeType: "text/plain", Owner: "system"})
+	if err != nil {
+		t.Fatalf("UploadFileMetadata: %v", err)
+	}
+	file, err := s.GetFileMetadataByName(context.Background(), filename)
+	if err != nil {
+		t.Fatalf("GetFileMetadataByName: %v", err)
+	}
+	return file.ID
+}
+
+func saveTestEmbeddings(t *testing.T, s *SQLite, fileID int64, chunks ...types.EmbeddedChunk) {
+	t.Helper()
+	if err := s.SaveEmbeddings(fileID, "test-model", chunks); err != nil {
+		t.Fatalf("SaveEmbeddings: %v", err)
+	}
+}
+
+func matchedFiles(matches []types.SemanticMatch) []string {
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.File.Filename)
+	}
+	return names
+}
+
+func TestSearchEmbeddingsRanking(t *testing.T) {
+	s := newTestStorage(t)
+	near := addTestFile(t, s, "close.txt")
+	half := addTestFile(t, s, "half.txt")
+	opposite := addTestFile(t, s, "opposite.txt")
+	saveTestEmbeddings(t, s, near,
+		types.EmbeddedChunk{Index: 0, Content: "unrelated", Vector: []float32{0, 1}},
+		types.EmbeddedChunk{Index: 1, Content: "best", Vector: []float32{1, 0.1}},
+	)
+	saveTestEmbeddings(t, s, half, types.EmbeddedChunk{Index: 0, Content: "half", Vector: []float32{1, 1}})
+	saveTestEmbeddings(t, s, opposite, types.EmbeddedChunk{Index: 0, Content: "opposite", Vector: []float32{-1, 0}})
+	// vectors of another model are never compared
+	if err := s.SaveEmbeddings(opposite, "other-model", []types.EmbeddedChunk{{Content: "other", Vector: []float32{1, 0}}}); err != nil {
+		t.Fatalf("SaveEmbeddings: %v", err)
+	}
+
+	matches, err := s.SearchEmbeddings(context.Background(), "", "test-model", []float32{1, 0}, 10)
+	if err != nil {
+		t.Fatalf("SearchEmbeddings: %v", err)
+	}
+	if got, want := matchedFiles(matches), []string{"close.txt", "half.txt", "opposite.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	if matches[0].Chunk != "best" {
+		t.Errorf("chunk of the best file = %q, want its best chunk", matches[0].Chunk)
+	}
+	if !(matches[0].Score > matches[1].Score && matches[1].Score > matches[2].Score) {
+		t.Errorf("scores %v, %v, %v are not in decreasing order", matches[0].Score, matches[1].Score, matches[2].Score)
+	}
+
+	matches, err = s.SearchEmbeddings(context.Background(), "", "test-model", []float32{1, 0}, 2)
+	if err != nil {
+		t.Fatalf("SearchEmbeddings: %v", err)
+	}
+	if got, want := matchedFiles(matches), []string{"close.txt", "half.txt"}; !slices.Equal(got, want) {
+		t.Errorf("files with limit 2 = %v, want %v", got, want)
+	}
+}
+
+func TestSearchEmbeddingsOnlyReadableFiles(t *testing.T) {
+	s := newTestStorage(t)
+	secret := addTestFile(t, s, "secret.txt")
+	shared := addTestFile(t, s, "shared.txt")
+	saveTestEmbeddings(t, s, secret, types.EmbeddedChunk{Content: "secret", Vector: []float32{1, 0}})
+	saveTestEmbeddings(t, s, shared, types.EmbeddedChunk{Content: "shared", Vector: []float32{0, 1}})
+	if err := s.CreatePermission("reader", "file", "read", strconv.FormatInt(shared, 10)); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+
+	// the best match is left out rather than taking one of the limit
+	matches, err := s.SearchEmbeddings(context.Background(), "reader", "test-model", []float32{1, 0}, 1)
+	if err != nil {
+		t.Fatalf("SearchEmbeddings: %v", err)
+	}
+	if got, want := matchedFiles(matches), []string{"shared.txt"}; !slices.Equal(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestSaveEmbeddingsOfDeletedFile(t *testing.T) {
+	s := newTestStorage(t)
+	id := addTestFile(t, s, "gone.txt")
+	if err := s.DeleteFileMetadata("gone.txt"); err != nil {
+		t.Fatalf("DeleteFileMetadata: %v", err)
+	}
+
+	saveTestEmbeddings(t, s, id, types.EmbeddedChunk{Content: "gone", Vector: []float32{1, 0}})
+	var count int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM embeddings").Scan(&count); err != nil {
+		t.Fatalf("count embeddings: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d embeddings were saved for a deleted file", count)
+	}
+}
+
+func TestDeleteClientDropsPermissions(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateClient("client", "agent", "hash"); err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	if err := s.CreatePermission("client", "file", "read", ""); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+	if err := s.DeleteClient("client"); err != nil {
+		t.Fatalf("DeleteClient: %v", err)
+	}
+
+	if err := s.CreateClient("client", "agent", "other-hash"); err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	if allowed, err := s.CheckPermission(context.Background(), "client", "file", "read"); err != nil || allowed {
+		t.Errorf("CheckPermission = %v, %v: a client created again inherited the grants of the deleted one", allowed, err)
+	}
+}
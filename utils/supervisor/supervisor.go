@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// Component is a long running part of Panes. Run blocks until ctx is cancelled or the component stops on its own
+type Component struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Supervisor runs components concurrently and stops all of them as soon as one stops or the parent context is cancelled
+type Supervisor struct {
+	store           *storage.SQLite
+	shutdownTimeout time.Duration
+	components      []Component
+}
+
+type exit struct {
+	name string
+	err  error
+}
+
+// New creates a supervisor that reports component failures to the logs table and gives components shutdownTimeout to stop
+func New(store *storage.SQLite, shutdownTimeout time.Duration) *Supervisor {
+	return &Supervisor{store: store, shutdownTimeout: shutdownTimeout}
+}
+
+// Add registers a component to be started by Run
+func (s *Supervisor) Add(name string, run func(ctx context.Context) error) {
+	s.components = append(s.components, Component{Name: name, Run: run})
+}
+
+// Run starts every component and blocks until all of them stopped, returning the failures of those that did not stop cleanly
+func (s *Supervisor) Run(ctx context.Context) error {
+	if len(s.components) == 0 {
+		return errors.New("no components to run")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	exits := make(chan exit, len(s.components))
+	running := make(map[string]bool, len(s.components))
+	for _, component := range s.components {
+		running[component.Name] = true
+		go func() {
+			slog.Info("Starting component", "component", component.Name)
+			exits <- exit{name: component.Name, err: component.Run(ctx)}
+		}()
+	}
+
+	// Wait for a shutdown signal or for the first component to stop
+	var errs []error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, stopping components", "timeout", s.shutdownTimeout)
+	case e := <-exits:
+		delete(running, e.name)
+		errs = append(errs, s.report(e, false))
+		cancel()
+	}
+
+	// Give the remaining components the shutdown deadline to stop
+	deadline := time.NewTimer(s.shutdownTimeout)
+	defer deadline.Stop()
+	for len(running) > 0 {
+		select {
+		case e := <-exits:
+			delete(running, e.name)
+			errs = append(errs, s.report(e, true))
+		case <-deadline.C:
+			for name := range running {
+				errs = append(errs, s.report(exit{name: name, err: errors.New("did not stop before the shutdown deadline")}, true))
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// report logs how a component stopped and returns its error, if it failed.
+// A component stopping without error before shutdown is not a failure, e.g. the stdio client closing stdin
+func (s *Supervisor) report(e exit, shuttingDown bool) error {
+	if e.err == nil || (shuttingDown && errors.Is(e.err, context.Canceled)) {
+		slog.Info("Component stopped", "component", e.name)
+		if !shuttingDown {
+			s.log(fmt.Sprintf("%s stopped, shutting down the other components", e.name), "info")
+		}
+		return nil
+	}
+
+	err := fmt.Errorf("%s: %w", e.name, e.err)
+	slog.Error("Component failed", "component", e.name, "error", e.err)
+	s.log(fmt.Sprintf("%s failed: %v", e.name, e.err), "error")
+	return err
+}
+
+func (s *Supervisor) log(message, logType string) {
+	err := s.store.CreateLogEntry(types.LogEntry{
+		Message:    message,
+		Type:       logType,
+		Action:     "supervise",
+		ClientName: "admin",
+	})
+	if err != nil {
+		slog.Error("Failed to log component status", "error", err)
+	}
+}
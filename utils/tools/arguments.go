@@ -0,0 +1,172 @@
+// Package tools builds MCP tools from the tools declared in the config
+package tools
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Argument types a config-declared tool can use
+const (
+	typeString  = "string"
+	typeNumber  = "number"
+	typeInteger = "integer"
+	typeBoolean = "boolean"
+)
+
+// placeholder matches {{argument_name}}, allowing spaces inside the braces
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// arguments is the validated input schema of a config-declared tool
+type arguments struct {
+	declared []config.ToolArgument
+	patterns map[string]*regexp.Regexp
+}
+
+// newArguments checks the argument declarations of a tool and compiles their patterns
+func newArguments(declared []config.ToolArgument) (*arguments, error) {
+	a := &arguments{patterns: make(map[string]*regexp.Regexp)}
+	seen := make(map[string]bool)
+	for _, argument := range declared {
+		if argument.Name == "" {
+			return nil, fmt.Errorf("argument name is required")
+		}
+		if seen[argument.Name] {
+			return nil, fmt.Errorf("argument %q is declared twice", argument.Name)
+		}
+		seen[argument.Name] = true
+
+		if argument.Type == "" {
+			argument.Type = typeString
+		}
+		switch argument.Type {
+		case typeString:
+		case typeNumber, typeInteger, typeBoolean:
+			if len(argument.Enum) > 0 || argument.Pattern != "" {
+				return nil, fmt.Errorf("argument %q: enum and pattern only apply to string arguments", argument.Name)
+			}
+		default:
+			return nil, fmt.Errorf("argument %q has unknown type %q", argument.Name, argument.Type)
+		}
+		if argument.Pattern != "" {
+			pattern, err := regexp.Compile(argument.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("argument %q: invalid pattern: %w", argument.Name, err)
+			}
+			a.patterns[argument.Name] = pattern
+		}
+		a.declared = append(a.declared, argument)
+	}
+	return a, nil
+}
+
+// checkTemplate makes sure every placeholder of a template refers to a declared argument
+func (a *arguments) checkTemplate(template string) error {
+	for _, match := range placeholder.FindAllStringSubmatch(template, -1) {
+		if !slices.ContainsFunc(a.declared, func(argument config.ToolArgument) bool { return argument.Name == match[1] }) {
+			return fmt.Errorf("placeholder {{%s}} is not a declared argument", match[1])
+		}
+	}
+	return nil
+}
+
+// toolOptions describes the arguments as the tool's input schema
+func (a *arguments) toolOptions() []mcp.ToolOption {
+	var options []mcp.ToolOption
+	for _, argument := range a.declared {
+		properties := []mcp.PropertyOption{mcp.Description(argument.Description)}
+		if argument.Required {
+			properties = append(properties, mcp.Required())
+		}
+		switch argument.Type {
+		case typeString:
+			if len(argument.Enum) > 0 {
+				properties = append(properties, mcp.Enum(argument.Enum...))
+			}
+			if argument.Pattern != "" {
+				properties = append(properties, mcp.Pattern(argument.Pattern))
+			}
+			options = append(options, mcp.WithString(argument.Name, properties...))
+		case typeNumber:
+			options = append(options, mcp.WithNumber(argument.Name, properties...))
+		case typeInteger:
+			properties = append(properties, func(schema map[string]any) { schema["type"] = typeInteger })
+			options = append(options, mcp.WithNumber(argument.Name, properties...))
+		case typeBoolean:
+			options = append(options, mcp.WithBoolean(argument.Name, properties...))
+		}
+	}
+	return options
+}
+
+// validate checks the arguments of a tool call against the declarations and returns them formatted as strings.
+// Optional arguments that were not given are left out
+func (a *arguments) validate(given map[string]any) (map[string]string, error) {
+	for name := range given {
+		if !slices.ContainsFunc(a.declared, func(argument config.ToolArgument) bool { return argument.Name == name }) {
+			return nil, fmt.Errorf("unknown argument %q", name)
+		}
+	}
+
+	values := make(map[string]string, len(a.declared))
+	for _, argument := range a.declared {
+		value, ok := given[argument.Name]
+		if !ok || value == nil {
+			if argument.Required {
+				return nil, fmt.Errorf("missing required argument %q", argument.Name)
+			}
+			continue
+		}
+
+		switch argument.Type {
+		case typeString:
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a string", argument.Name)
+			}
+			if len(argument.Enum) > 0 && !slices.Contains(argument.Enum, s) {
+				return nil, fmt.Errorf("argument %q must be one of %s", argument.Name, strings.Join(argument.Enum, ", "))
+			}
+			if pattern, ok := a.patterns[argument.Name]; ok && !pattern.MatchString(s) {
+				return nil, fmt.Errorf("argument %q must match %s", argument.Name, argument.Pattern)
+			}
+			values[argument.Name] = s
+		case typeNumber, typeInteger:
+			n, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a number", argument.Name)
+			}
+			if argument.Type == typeInteger && n != math.Trunc(n) {
+				return nil, fmt.Errorf("argument %q must be an integer", argument.Name)
+			}
+			values[argument.Name] = strconv.FormatFloat(n, 'f', -1, 64)
+		case typeBoolean:
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be a boolean", argument.Name)
+			}
+			values[argument.Name] = strconv.FormatBool(b)
+		}
+	}
+	return values, nil
+}
+
+// expand replaces the placeholders of a template with the argument values, reporting false when one of them was not given
+func expand(template string, values map[string]string) (string, bool) {
+	complete := true
+	expanded := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		value, ok := values[placeholder.FindStringSubmatch(match)[1]]
+		if !ok {
+			complete = false
+		}
+		return value
+	})
+	return expanded, complete
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultCommandTimeout = 30 * time.Second
+	defaultMaxOutputBytes = 64 << 10 // 64 KB
+)
+
+// CommandProvider serves the command_tools of the config under the "commands" tool provider
+type CommandProvider struct {
+	tools []config.CommandToolConfig
+}
+
+// NewCommandProvider creates the provider of the tools declared under command_tools
+func NewCommandProvider(tools []config.CommandToolConfig) *CommandProvider {
+	return &CommandProvider{tools: tools}
+}
+
+func (p *CommandProvider) Name() string {
+	return "commands"
+}
+
+func (p *CommandProvider) Tools(store *storage.SQLite) ([]provider.Tool, error) {
+	tools := make([]provider.Tool, 0, len(p.tools))
+	for _, declared := range p.tools {
+		tool, err := newCommandTool(declared)
+		if err != nil {
+			return nil, fmt.Errorf("command tool %q: %w", declared.Name, err)
+		}
+		options := append([]mcp.ToolOption{
+			mcp.WithDescription(declared.Description),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		}, tool.arguments.toolOptions()...)
+		tools = append(tools, provider.Tool{
+			Definition: mcp.NewTool(declared.Name, options...),
+			Handler:    tool.call,
+		})
+	}
+	return tools, nil
+}
+
+// commandTool runs a declared executable with the arguments of a tool call
+type commandTool struct {
+	config.CommandToolConfig
+	arguments *arguments
+}
+
+func newCommandTool(declared config.CommandToolConfig) (*commandTool, error) {
+	if declared.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if declared.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	arguments, err := newArguments(declared.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range declared.Args {
+		if err := arguments.checkTemplate(arg); err != nil {
+			return nil, err
+		}
+	}
+	if declared.Timeout <= 0 {
+		declared.Timeout = defaultCommandTimeout
+	}
+	if declared.MaxOutputBytes <= 0 {
+		declared.MaxOutputBytes = defaultMaxOutputBytes
+	}
+	return &commandTool{CommandToolConfig: declared, arguments: arguments}, nil
+}
+
+func (t *commandTool) call(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	values, err := t.arguments.validate(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// a value like --output=/etc/passwd would be read as an option of the executable
+	for _, argument := range t.arguments.declared {
+		if value, ok := values[argument.Name]; ok && strings.HasPrefix(value, "-") && !argument.AllowLeadingDash {
+			return mcp.NewToolResultErrorf("argument %q must not start with \"-\"", argument.Name), nil
+		}
+	}
+
+	// arguments are passed to the executable as they are, no shell ever sees them
+	var args []string
+	for _, arg := range t.Args {
+		if expanded, complete := expand(arg, values); complete {
+			args = append(args, expanded)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.Command, args...)
+	cmd.Dir = t.WorkingDir
+	cmd.Env = allowedEnv(t.Env)
+	cmd.WaitDelay = time.Second
+	budget := &outputBudget{left: t.MaxOutputBytes}
+	stdout := &cappedBuffer{budget: budget}
+	stderr := &cappedBuffer{budget: budget}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	slog.Info("Running command tool", "tool", t.Name, "client", caller.ClientName, "command", t.Command, "args", args)
+	started := time.Now()
+	err = cmd.Run()
+
+	if stdout.dropped > 0 || stderr.dropped > 0 {
+		provider.Notify(ctx, mcp.LoggingLevelNotice, provider.EventTruncated, fmt.Sprintf("%s output was cut after %d bytes", t.Name, t.MaxOutputBytes),
+			"tool", t.Name, "dropped_bytes", stdout.dropped+stderr.dropped)
+	}
+	output := stdout.String()
+	if stderr.Len() > 0 || stderr.dropped > 0 {
+		output += "\n[stderr]\n" + stderr.String()
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return mcp.NewToolResultError(fmt.Sprintf("%s\n[timed out after %s]", output, t.Timeout)), nil
+	case err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return mcp.NewToolResultError(fmt.Sprintf("%s\n[exit code %d]", output, exitErr.ExitCode())), nil
+		}
+		slog.Error("Failed to run command tool", "tool", t.Name, "error", err)
+		return mcp.NewToolResultErrorf("could not run %s: %v", t.Name, err), nil
+	}
+	slog.Info("Command tool finished", "tool", t.Name, "duration", time.Since(started))
+	return mcp.NewToolResultText(output), nil
+}
+
+// allowedEnv picks the allowlisted variables out of the environment of Panes
+func allowedEnv(names []string) []string {
+	env := []string{}
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// outputBudget is the number of bytes the outputs of a command may still keep, stdout and stderr draw from the same one
+type outputBudget struct {
+	mu   sync.Mutex
+	left int
+}
+
+// take reserves up to n bytes of the budget and returns how many were granted
+func (o *outputBudget) take(n int) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	granted := min(n, o.left)
+	o.left -= granted
+	return granted
+}
+
+// cappedBuffer keeps what its budget allows of the bytes written to it and counts the rest
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	budget  *outputBudget
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	kept := b.budget.take(n)
+	b.dropped += n - kept
+	b.buf.Write(p[:kept])
+	return n, nil
+}
+
+func (b *cappedBuffer) Len() int {
+	return b.buf.Len()
+}
+
+// String returns the kept output, noting how much of it was cut off
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s\n[output truncated, %d more bytes not shown]", b.buf.String(), b.dropped)
+}
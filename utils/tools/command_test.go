@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callCommandTool(t *testing.T, declared config.CommandToolConfig, arguments map[string]any) (string, bool) {
+	t.Helper()
+	if declared.Name == "" {
+		declared.Name = "run"
+	}
+	tool, err := newCommandTool(declared)
+	if err != nil {
+		t.Fatalf("newCommandTool: %v", err)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = declared.Name
+	request.Params.Arguments = arguments
+	result, err := tool.call(context.Background(), provider.Caller{ClientName: "test"}, request)
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("result content is %T, not text", result.Content[0])
+	}
+	return text.Text, result.IsError
+}
+
+func TestCommandToolArguments(t *testing.T) {
+	declared := config.CommandToolConfig{
+		Command:   "printf",
+		Args:      []string{"%s|", "{{value}}"},
+		Arguments: []config.ToolArgument{{Name: "value", Required: true}},
+	}
+
+	// no shell sees the value
+	text, isError := callCommandTool(t, declared, map[string]any{"value": "a b; echo $HOME"})
+	if isError || text != "a b; echo $HOME|" {
+		t.Errorf("text = %q (error %v), want the value passed as one argument", text, isError)
+	}
+
+	text, isError = callCommandTool(t, declared, map[string]any{"value": "--version"})
+	if !isError || !strings.Contains(text, `must not start with "-"`) {
+		t.Errorf("text = %q (error %v), want a value starting with - refused", text, isError)
+	}
+
+	declared.Arguments[0].AllowLeadingDash = true
+	text, isError = callCommandTool(t, declared, map[string]any{"value": "-n"})
+	if isError || text != "-n|" {
+		t.Errorf("text = %q (error %v), want the value allowed by allow_leading_dash", text, isError)
+	}
+}
+
+func TestCommandToolEnvAllowlist(t *testing.T) {
+	t.Setenv("PANES_TEST_ALLOWED", "yes")
+	t.Setenv("PANES_TEST_SECRET", "s3cret")
+
+	text, isError := callCommandTool(t, config.CommandToolConfig{Command: "env", Env: []string{"PANES_TEST_ALLOWED", "PANES_TEST_UNSET"}}, nil)
+	if isError || text != "PANES_TEST_ALLOWED=yes\n" {
+		t.Errorf("environment = %q (error %v), want only the allowlisted variable", text, isError)
+	}
+}
+
+func TestCommandToolTruncation(t *testing.T) {
+	text, isError := callCommandTool(t, config.CommandToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "printf '%0100d' 0"},
+		MaxOutputBytes: 10,
+	}, nil)
+	if isError {
+		t.Fatalf("call failed: %s", text)
+	}
+	if want := strings.Repeat("0", 10) + "\n[output truncated, 90 more bytes not shown]"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	// stdout and stderr share the budget
+	text, isError = callCommandTool(t, config.CommandToolConfig{
+		Command:        "sh",
+		Args:           []string{"-c", "printf '%010d' 0; printf '%010d' 1 >&2"},
+		MaxOutputBytes: 15,
+	}, nil)
+	if isError {
+		t.Fatalf("call failed: %s", text)
+	}
+	if kept := strings.Count(text, "0") + strings.Count(text, "1"); kept != 15 {
+		t.Errorf("%d bytes of output kept in %q, want 15", kept, text)
+	}
+	if !strings.Contains(text, "[stderr]") || !strings.Contains(text, "more bytes not shown") {
+		t.Errorf("text = %q, want the stderr section and a truncation note", text)
+	}
+}
+
+func TestCommandToolExitCode(t *testing.T) {
+	text, isError := callCommandTool(t, config.CommandToolConfig{Command: "sh", Args: []string{"-c", "echo failing >&2; exit 3"}}, nil)
+	if !isError || !strings.Contains(text, "[stderr]\nfailing") || !strings.Contains(text, "[exit code 3]") {
+		t.Errorf("text = %q (error %v), want stderr and the exit code as an error", text, isError)
+	}
+}
@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultHTTPTimeout      = 30 * time.Second
+	defaultMaxResponseBytes = 64 << 10 // 64 KB
+)
+
+// HTTPProvider serves the http_tools of the config under the "http" tool provider
+type HTTPProvider struct {
+	tools  []config.HTTPToolConfig
+	client *http.Client
+}
+
+// NewHTTPProvider creates the provider of the tools declared under http_tools
+func NewHTTPProvider(tools []config.HTTPToolConfig) *HTTPProvider {
+	return &HTTPProvider{tools: tools, client: &http.Client{}}
+}
+
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+func (p *HTTPProvider) Tools(store *storage.SQLite) ([]provider.Tool, error) {
+	tools := make([]provider.Tool, 0, len(p.tools))
+	for _, declared := range p.tools {
+		tool, err := newHTTPTool(declared, p.client)
+		if err != nil {
+			return nil, fmt.Errorf("http tool %q: %w", declared.Name, err)
+		}
+		options := append([]mcp.ToolOption{
+			mcp.WithDescription(declared.Description),
+			mcp.WithReadOnlyHintAnnotation(tool.Method == http.MethodGet || tool.Method == http.MethodHead),
+			mcp.WithDestructiveHintAnnotation(tool.Method != http.MethodGet && tool.Method != http.MethodHead),
+			mcp.WithOpenWorldHintAnnotation(true),
+		}, tool.arguments.toolOptions()...)
+		tools = append(tools, provider.Tool{
+			Definition: mcp.NewTool(declared.Name, options...),
+			Handler:    tool.call,
+		})
+	}
+	return tools, nil
+}
+
+// httpTool calls a declared endpoint with the arguments of a tool call
+type httpTool struct {
+	config.HTTPToolConfig
+	arguments *arguments
+	headers   http.Header
+	extract   []pathStep
+	client    *http.Client
+}
+
+func newHTTPTool(declared config.HTTPToolConfig, client *http.Client) (*httpTool, error) {
+	if declared.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if declared.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	declared.Method = strings.ToUpper(declared.Method)
+	if declared.Method == "" {
+		declared.Method = http.MethodGet
+	}
+	if declared.Timeout <= 0 {
+		declared.Timeout = defaultHTTPTimeout
+	}
+	if declared.MaxResponseBytes <= 0 {
+		declared.MaxResponseBytes = defaultMaxResponseBytes
+	}
+
+	arguments, err := newArguments(declared.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	if err := arguments.checkTemplate(declared.URL); err != nil {
+		return nil, err
+	}
+	if _, err := url.Parse(placeholder.ReplaceAllString(declared.URL, "x")); err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	// secrets are resolved once, so a missing variable is reported at startup rather than on the first call
+	headers := make(http.Header)
+	for name, value := range declared.Headers {
+		resolved, err := config.ExpandEnv(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		headers.Set(name, resolved)
+	}
+
+	tool := &httpTool{HTTPToolConfig: declared, arguments: arguments, headers: headers, client: client}
+	if declared.Extract != "" {
+		if tool.extract, err = parsePath(declared.Extract); err != nil {
+			return nil, err
+		}
+	}
+	return tool, nil
+}
+
+// sendsBody reports whether the arguments of a call are sent as a JSON body
+func (t *httpTool) sendsBody() bool {
+	switch t.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return false
+	}
+	return true
+}
+
+// buildURL fills the URL template, escaping values as path segments before the query and as query values after it.
+// A path segment made of argument values alone must not come out empty, "." or "..", which would leave the declared path
+func (t *httpTool) buildURL(values map[string]string) (string, error) {
+	path, query, hasQuery := strings.Cut(t.URL, "?")
+	escape := func(template string, escaper func(string) string) string {
+		return placeholder.ReplaceAllStringFunc(template, func(match string) string {
+			return escaper(values[placeholder.FindStringSubmatch(match)[1]])
+		})
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !placeholder.MatchString(segment) {
+			continue
+		}
+		segments[i] = escape(segment, url.PathEscape)
+		switch segments[i] {
+		case "", ".", "..":
+			return "", fmt.Errorf("arguments of %s would make the URL path segment %q %q", t.Name, segment, segments[i])
+		}
+	}
+	built := strings.Join(segments, "/")
+	if hasQuery {
+		built += "?" + escape(query, url.QueryEscape)
+	}
+	return built, nil
+}
+
+func (t *httpTool) call(ctx context.Context, caller provider.Caller, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	values, err := t.arguments.validate(request.GetArguments())
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var body io.Reader
+	if t.sendsBody() {
+		data, err := json.Marshal(request.GetArguments())
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("could not encode arguments", err), nil
+		}
+		body = bytes.NewReader(data)
+	}
+
+	target, err := t.buildURL(values)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, t.Method, target, body)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("could not build request", err), nil
+	}
+	req.Header = t.headers.Clone()
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	slog.Info("Calling HTTP tool", "tool", t.Name, "client", caller.ClientName, "method", t.Method, "url", req.URL.Redacted())
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return mcp.NewToolResultErrorf("%s timed out after %s", t.Name, t.Timeout), nil
+		}
+		slog.Error("HTTP tool request failed", "tool", t.Name, "error", err)
+		return mcp.NewToolResultErrorf("request to %s failed: %v", t.Name, err), nil
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(t.MaxResponseBytes)+1))
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("could not read response", err), nil
+	}
+	truncated := len(data) > t.MaxResponseBytes
+	if truncated {
+		data = data[:t.MaxResponseBytes]
+		provider.Notify(ctx, mcp.LoggingLevelNotice, provider.EventTruncated, fmt.Sprintf("%s response was cut after %d bytes", t.Name, t.MaxResponseBytes),
+			"tool", t.Name)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return mcp.NewToolResultError(fmt.Sprintf("%s responded %s\n%s", t.Name, resp.Status, data)), nil
+	}
+	if t.extract == nil {
+		if truncated {
+			return mcp.NewToolResultText(fmt.Sprintf("%s\n[response truncated after %d bytes]", data, t.MaxResponseBytes)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+
+	if truncated {
+		return mcp.NewToolResultErrorf("response is larger than %d bytes, nothing could be extracted", t.MaxResponseBytes), nil
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return mcp.NewToolResultErrorFromErr("response is not JSON", err), nil
+	}
+	extracted, err := extractPath(decoded, t.extract)
+	if err != nil {
+		return mcp.NewToolResultErrorf("could not extract %s: %v", t.Extract, err), nil
+	}
+	if text, ok := extracted.(string); ok {
+		return mcp.NewToolResultText(text), nil
+	}
+	encoded, err := json.MarshalIndent(extracted, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(encoded)), nil
+}
@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aaryansinhaa/panes/utils/config"
+	"github.com/aaryansinhaa/panes/utils/mcp/provider"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// recorded is what the test server saw of the last request
+type recorded struct {
+	method string
+	uri    string
+	header http.Header
+	body   string
+}
+
+// newTestServer answers every request with status and body, recording the request
+func newTestServer(t *testing.T, status int, body string) (*httptest.Server, *recorded) {
+	t.Helper()
+	seen := &recorded{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		*seen = recorded{method: r.Method, uri: r.URL.RequestURI(), header: r.Header.Clone(), body: string(data)}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, seen
+}
+
+func callHTTPTool(t *testing.T, declared config.HTTPToolConfig, client *http.Client, arguments map[string]any) (string, bool) {
+	t.Helper()
+	if declared.Name == "" {
+		declared.Name = "fetch"
+	}
+	tool, err := newHTTPTool(declared, client)
+	if err != nil {
+		t.Fatalf("newHTTPTool: %v", err)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Name = declared.Name
+	request.Params.Arguments = arguments
+	result, err := tool.call(context.Background(), provider.Caller{ClientName: "test"}, request)
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("result content is %T, not text", result.Content[0])
+	}
+	return text.Text, result.IsError
+}
+
+func TestHTTPToolURLTemplate(t *testing.T) {
+	srv, seen := newTestServer(t, http.StatusOK, "ok")
+	declared := config.HTTPToolConfig{
+		URL: srv.URL + "/repos/{{owner}}/issues?q={{query}}",
+		Arguments: []config.ToolArgument{
+			{Name: "owner", Required: true},
+			{Name: "query", Required: true},
+		},
+	}
+
+	if _, isError := callHTTPTool(t, declared, srv.Client(), map[string]any{"owner": "a b/c", "query": "x&y=z"}); isError {
+		t.Fatal("call failed")
+	}
+	if want := "/repos/a%20b%2Fc/issues?q=x%26y%3Dz"; seen.uri != want {
+		t.Errorf("request URI = %q, want %q", seen.uri, want)
+	}
+	if seen.method != http.MethodGet {
+		t.Errorf("method = %s, want GET by default", seen.method)
+	}
+}
+
+func TestHTTPToolRejectsDotSegments(t *testing.T) {
+	srv, seen := newTestServer(t, http.StatusOK, "ok")
+	declared := config.HTTPToolConfig{
+		URL:       srv.URL + "/repos/{{owner}}/issues",
+		Arguments: []config.ToolArgument{{Name: "owner"}},
+	}
+
+	for _, owner := range []any{".", "..", ""} {
+		text, isError := callHTTPTool(t, declared, srv.Client(), map[string]any{"owner": owner})
+		if !isError {
+			t.Errorf("owner %q: call succeeded with %q, want an error", owner, text)
+		}
+	}
+	if _, isError := callHTTPTool(t, declared, srv.Client(), map[string]any{}); !isError {
+		t.Error("missing optional path argument: call succeeded, want an error")
+	}
+	if seen.method != "" {
+		t.Errorf("a request reached the server: %s %s", seen.method, seen.uri)
+	}
+}
+
+func TestHTTPToolMethodAndBody(t *testing.T) {
+	srv, seen := newTestServer(t, http.StatusCreated, `{"id":7}`)
+	declared := config.HTTPToolConfig{
+		Method: "post",
+		URL:    srv.URL + "/items",
+		Arguments: []config.ToolArgument{
+			{Name: "title", Required: true},
+			{Name: "count", Type: "integer"},
+		},
+	}
+
+	if _, isError := callHTTPTool(t, declared, srv.Client(), map[string]any{"title": "hi", "count": float64(2)}); isError {
+		t.Fatal("call failed")
+	}
+	if seen.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", seen.method)
+	}
+	if got := seen.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(seen.body), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", seen.body, err)
+	}
+	if body["title"] != "hi" || body["count"] != float64(2) {
+		t.Errorf("body = %v, want the arguments", body)
+	}
+}
+
+func TestHTTPToolEnvHeaders(t *testing.T) {
+	t.Setenv("PANES_TEST_TOKEN", "s3cret")
+	srv, seen := newTestServer(t, http.StatusOK, "ok")
+	declared := config.HTTPToolConfig{
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer ${PANES_TEST_TOKEN}"},
+	}
+
+	if _, isError := callHTTPTool(t, declared, srv.Client(), nil); isError {
+		t.Fatal("call failed")
+	}
+	if got := seen.header.Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want the expanded secret", got)
+	}
+
+	declared.Headers = map[string]string{"Authorization": "Bearer ${PANES_TEST_UNSET}"}
+	if _, err := newHTTPTool(declared, srv.Client()); err == nil {
+		t.Error("newHTTPTool accepted a header using an unset variable")
+	}
+}
+
+func TestHTTPToolExtract(t *testing.T) {
+	srv, _ := newTestServer(t, http.StatusOK, `{"items":[{"name":"first"},{"name":"second"}]}`)
+	tests := []struct {
+		extract string
+		want    string
+	}{
+		{"$.items[1].name", "second"},
+		{"$.items[*].name", "[\n  \"first\",\n  \"second\"\n]"},
+	}
+	for _, tt := range tests {
+		text, isError := callHTTPTool(t, config.HTTPToolConfig{URL: srv.URL, Extract: tt.extract}, srv.Client(), nil)
+		if isError || text != tt.want {
+			t.Errorf("extract %s = %q (error %v), want %q", tt.extract, text, isError, tt.want)
+		}
+	}
+
+	if _, isError := callHTTPTool(t, config.HTTPToolConfig{URL: srv.URL, Extract: "$.missing"}, srv.Client(), nil); !isError {
+		t.Error("extracting a missing key succeeded")
+	}
+}
+
+func TestHTTPToolTruncation(t *testing.T) {
+	srv, _ := newTestServer(t, http.StatusOK, strings.Repeat("a", 100))
+
+	text, isError := callHTTPTool(t, config.HTTPToolConfig{URL: srv.URL, MaxResponseBytes: 10}, srv.Client(), nil)
+	if isError {
+		t.Fatal("call failed")
+	}
+	if want := strings.Repeat("a", 10) + "\n[response truncated after 10 bytes]"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	if _, isError := callHTTPTool(t, config.HTTPToolConfig{URL: srv.URL, MaxResponseBytes: 10, Extract: "$.a"}, srv.Client(), nil); !isError {
+		t.Error("extracting from a truncated response succeeded")
+	}
+}
+
+func TestHTTPToolErrorStatus(t *testing.T) {
+	srv, _ := newTestServer(t, http.StatusNotFound, "no such item")
+
+	text, isError := callHTTPTool(t, config.HTTPToolConfig{URL: srv.URL}, srv.Client(), nil)
+	if !isError || !strings.Contains(text, "404") || !strings.Contains(text, "no such item") {
+		t.Errorf("text = %q (error %v), want the status and body as an error", text, isError)
+	}
+}
@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a JSONPath-style expression: an object key, an array index or every array element
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath parses the subset of JSONPath used to extract values from responses:
+// $.key, $.key.nested, $.list[2], $.list[*].name and $["key with spaces"]
+func parsePath(expression string) ([]pathStep, error) {
+	rest := strings.TrimSpace(expression)
+	if !strings.HasPrefix(rest, "$") {
+		return nil, fmt.Errorf("path %q must start with $", expression)
+	}
+	rest = rest[1:]
+
+	var steps []pathStep
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q has an empty key", expression)
+			}
+			steps = append(steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("path %q has an unclosed [", expression)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("path %q has an invalid index %q", expression, inner)
+				}
+				steps = append(steps, pathStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("path %q is invalid near %q", expression, rest)
+		}
+	}
+	return steps, nil
+}
+
+// extractPath selects the value a parsed path points to in decoded JSON, a wildcard yields a list
+func extractPath(value any, steps []pathStep) (any, error) {
+	for i, step := range steps {
+		switch {
+		case step.wildcard:
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("[*] applied to a non-array value")
+			}
+			results := make([]any, 0, len(list))
+			for _, element := range list {
+				extracted, err := extractPath(element, steps[i+1:])
+				if err != nil {
+					continue
+				}
+				results = append(results, extracted)
+			}
+			return results, nil
+		case step.isIndex:
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("[%d] applied to a non-array value", step.index)
+			}
+			index := step.index
+			if index < 0 {
+				index += len(list)
+			}
+			if index < 0 || index >= len(list) {
+				return nil, fmt.Errorf("index %d is out of range", step.index)
+			}
+			value = list[index]
+		default:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %q applied to a non-object value", step.key)
+			}
+			next, ok := object[step.key]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", step.key)
+			}
+			value = next
+		}
+	}
+	return value, nil
+}
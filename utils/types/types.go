@@ -7,6 +7,8 @@ type LogEntry struct {
 	Timestamp  string `json:"timestamp"`
 	Action     string `json:"action"`
 	ClientName string `json:"client_name"`
+	Target     string `json:"target,omitempty"`      // tool, resource URI or prompt of an MCP request
+	DurationMs int64  `json:"duration_ms,omitempty"` // how long an MCP request took
 }
 
 type Client struct {
@@ -16,6 +18,43 @@ type Client struct {
 	ClientAPIHash string `json:"client_api_hash"` // encrypted/hashed API key
 	Active        bool   `json:"active"`
 	CreatedAt     string `json:"created_at"`
+	ClientLimits
+	ToolLimits map[string]ClientLimits `json:"tool_limits"` // replace the client limits for calls to the named tools
+	Usage      *ClientUsage            `json:"usage,omitempty"`
+}
+
+// ClientLimits throttles the MCP tool calls and resource reads of a client, zero values mean unlimited
+type ClientLimits struct {
+	RateLimit  float64 `json:"rate_limit"`  // calls per minute, refilled continuously
+	Burst      int     `json:"burst"`       // calls allowed at once, defaults to the per-minute rate
+	DailyQuota int64   `json:"daily_quota"` // calls per UTC day
+}
+
+// ClientUsage is how much of its daily quotas a client used today
+type ClientUsage struct {
+	Day   string                `json:"day"` // UTC, YYYY-MM-DD
+	Calls QuotaUsage            `json:"calls"`
+	Tools map[string]QuotaUsage `json:"tools,omitempty"` // tools with their own limits
+}
+
+// QuotaUsage counts the calls of a day against a quota
+type QuotaUsage struct {
+	Used      int64  `json:"used"`
+	Remaining *int64 `json:"remaining"` // null without a daily quota
+}
+
+// MCPSession is a connected MCP client session
+type MCPSession struct {
+	SessionID       string           `json:"session_id"`
+	Transport       string           `json:"transport"`           // stdio, streamable_http or sse
+	ClientID        string           `json:"client_id,omitempty"` // the authenticated client, empty on stdio
+	ClientName      string           `json:"client_name"`
+	ClientInfo      string           `json:"client_info"` // name and version the client software gave on initialize
+	ProtocolVersion string           `json:"protocol_version"`
+	StartedAt       string           `json:"started_at"`
+	LastActivityAt  string           `json:"last_activity_at"`
+	Requests        int64            `json:"requests"`
+	Calls           map[string]int64 `json:"calls"` // requests by MCP method
 }
 
 type FileMetadata struct {
@@ -29,6 +68,35 @@ type FileMetadata struct {
 	Owner        string `json:"owner"` // default 'system'
 }
 
+// Rendition is the plain-text or Markdown text extracted from an uploaded document, served to MCP clients instead of its bytes
+type Rendition struct {
+	FileID      int64  `json:"file_id"`
+	MimeType    string `json:"mime_type"`
+	Content     string `json:"content"`
+	ExtractedAt string `json:"extracted_at"`
+}
+
+// ContentMatch is a file found by content search
+type ContentMatch struct {
+	File    FileMetadata `json:"file"`
+	Snippet string       `json:"snippet"` // matching text, matched terms wrapped in **
+	Score   float64      `json:"score"`   // BM25 relevance, higher is better
+}
+
+// EmbeddedChunk is a piece of the text of a file with its embedding vector
+type EmbeddedChunk struct {
+	Index   int
+	Content string
+	Vector  []float32
+}
+
+// SemanticMatch is a file found by semantic search
+type SemanticMatch struct {
+	File  FileMetadata `json:"file"`
+	Chunk string       `json:"chunk"` // the part of the file text closest to the query
+	Score float64      `json:"score"` // cosine similarity, higher is better
+}
+
 type Permission struct {
 	ID         int64
 	ClientID   string `json:"client_id"`
@@ -38,3 +106,20 @@ type Permission struct {
 	CreatedAt  string `json:"created_at"`
 }
 
+type Prompt struct {
+	ID          int64
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Template    string           `json:"template"` // arguments are referenced as {{argument_name}}
+	Arguments   []PromptArgument `json:"arguments"`
+	Version     int64            `json:"version"` // bumped on every update
+	CreatedAt   string           `json:"created_at"`
+	UpdatedAt   string           `json:"updated_at"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"` // "string", "number" or "boolean"
+	Required    bool   `json:"required"`
+}
@@ -0,0 +1,41 @@
+package uploads
+
+import "sync"
+
+// locks serialises the writes to each uploaded file, whether uploaded through the REST API or written by MCP clients,
+// so concurrent writes cannot interleave their content or leave the index holding the text of an earlier write
+var locks = fileLocks{byName: make(map[string]*fileLock)}
+
+type fileLocks struct {
+	mu     sync.Mutex
+	byName map[string]*fileLock
+}
+
+// fileLock is the lock of one file, dropped once no write holds or waits for it
+type fileLock struct {
+	sync.Mutex
+	users int
+}
+
+// Lock waits for the other writes to the file stored for filename and returns the function ending this one
+func Lock(filename string) (unlock func()) {
+	filename = SanitizeFilename(filename)
+	locks.mu.Lock()
+	lock, ok := locks.byName[filename]
+	if !ok {
+		lock = &fileLock{}
+		locks.byName[filename] = lock
+	}
+	lock.users++
+	locks.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		locks.mu.Lock()
+		defer locks.mu.Unlock()
+		if lock.users--; lock.users == 0 {
+			delete(locks.byName, filename)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package uploads keeps the files uploaded to Panes, through the REST API or written by MCP clients, on disk and
+// their text in storage
+package uploads
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaryansinhaa/panes/utils/embeddings"
+	"github.com/aaryansinhaa/panes/utils/extract"
+	"github.com/aaryansinhaa/panes/utils/services/storage"
+	"github.com/aaryansinhaa/panes/utils/types"
+)
+
+// Dir is where uploaded files are stored
+const Dir = "./uploads"
+
+// SanitizeFilename turns an uploaded filename into the name of the file stored in Dir
+func SanitizeFilename(name string) string {
+	name = filepath.Base(name)
+	name = strings.ReplaceAll(name, " ", "_")
+	return name
+}
+
+// Path is where an uploaded file of the given name is stored
+func Path(filename string) string {
+	return filepath.Join(Dir, SanitizeFilename(filename))
+}
+
+// StoreText extracts the text of an uploaded document for MCP clients and indexes the text of the file for
+// content search, and for semantic search when it is configured. A file whose text cannot be extracted is still
+// uploaded, it is served as is
+func StoreText(ctx context.Context, store *storage.SQLite, index *embeddings.Index, filename string) {
+	file, err := store.GetFileMetadataByName(ctx, filename)
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(file.FilePath)
+	if err != nil {
+		slog.Error("Failed to read uploaded file", "filename", filename, "error", err)
+		return
+	}
+
+	var text string
+	if extract.Supported(file.MimeType) {
+		text = extractRendition(ctx, store, file, data)
+	} else {
+		// a replaced document may have left a rendition behind
+		store.DeleteRendition(file.ID)
+		if extract.IsText(file.MimeType) {
+			text = string(data)
+		}
+	}
+	if err := store.IndexFileContent(ctx, file.ID, file.Filename, text); err != nil {
+		slog.Error("Failed to index file content", "filename", filename, "error", err)
+	}
+	if index != nil {
+		index.IndexInBackground(file, text)
+	}
+}
+
+// extractRendition stores the rendition of a document and returns its text, empty when extraction failed
+func extractRendition(ctx context.Context, store *storage.SQLite, file types.FileMetadata, data []byte) string {
+	log := types.LogEntry{Action: "extract", ClientName: "admin"}
+	rendition, err := extract.Extract(file.MimeType, data)
+	if err == nil {
+		err = store.SaveRendition(ctx, types.Rendition{FileID: file.ID, MimeType: rendition.MimeType, Content: rendition.Text})
+	}
+	if err != nil {
+		slog.Error("Failed to extract file text", "filename", file.Filename, "error", err)
+		store.DeleteRendition(file.ID)
+		rendition.Text = ""
+		log.Message = "Failed to extract text of " + file.Filename + ": " + err.Error()
+		log.Type = "error"
+	} else {
+		log.Message = "Extracted text of " + file.Filename
+		log.Type = "success"
+	}
+	if err := store.CreateLogEntry(log); err != nil {
+		slog.Error("Failed to log extraction", "error", err)
+	}
+	return rendition.Text
+}